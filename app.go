@@ -3,22 +3,33 @@ package main
 import (
 	"coin-control/backend/auth"
 	"coin-control/backend/bybit"
+	"coin-control/backend/exchange"
 	"coin-control/backend/queue"
 	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// defaultExchange is used by bindings that do not take an exchange name
+const defaultExchange = bybit.ExchangeName
+
 // App represents the main application structure
 type App struct {
 	ctx                context.Context
 	authService        *auth.AuthService
-	bybitService       *bybit.BybitService
-	priceSubscriptions map[string]chan bybit.PriceData
+	exchanges          *exchange.Registry
+	priceSubscriptions map[string]*priceStream
 	priceMutex         sync.RWMutex
+	bookSubscriptions  map[string]*bookStream
+	bookMutex          sync.Mutex
+	tradeSubscriptions map[string]*tradeStream
+	tradeMutex         sync.Mutex
+	klineSubscriptions map[string]*klineStream
+	klineMutex         sync.Mutex
 	queue              *queue.Queue
 }
 
@@ -26,8 +37,11 @@ type App struct {
 func NewApp() *App {
 	return &App{
 		authService:        auth.NewAuthService(),
-		bybitService:       bybit.NewBybitService(),
-		priceSubscriptions: make(map[string]chan bybit.PriceData),
+		exchanges:          exchange.NewRegistry(),
+		priceSubscriptions: make(map[string]*priceStream),
+		bookSubscriptions:  make(map[string]*bookStream),
+		tradeSubscriptions: make(map[string]*tradeStream),
+		klineSubscriptions: make(map[string]*klineStream),
 	}
 }
 
@@ -46,18 +60,53 @@ func (a *App) Greet(name string) string {
 	return fmt.Sprintf("Hello %s, It's show time!", name)
 }
 
+// formatBindingError is the Wails ErrorFormatter. Exchange API errors are sent
+// as objects carrying a stable code; everything else stays a plain message.
+func formatBindingError(err error) any {
+	if payload, ok := bybit.AsErrorPayload(err); ok {
+		return payload
+	}
+	return err.Error()
+}
+
 // =============================================================================
 // Authentication methods
 // =============================================================================
 
 // Login authenticates a user with email/password
 func (a *App) Login(req auth.LoginRequest) (*auth.LoginResponse, error) {
-	return a.authService.Login(a.ctx, req)
+	resp, err := a.authService.Login(a.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Auth != nil {
+		a.selectMarkets(resp.Auth.UserID.String())
+	}
+	return resp, nil
 }
 
 // ValidateToken validates a JWT token and returns claims
 func (a *App) ValidateToken(token string) (*auth.Claims, error) {
-	return a.authService.ValidateToken(token)
+	claims, err := a.authService.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	a.selectMarkets(claims.UserID)
+	return claims, nil
+}
+
+// selectMarkets serves market data from the environment of the signed-in
+// user on every exchange where it depends on the user
+func (a *App) selectMarkets(userID string) {
+	for _, name := range a.exchanges.Names() {
+		connector, err := a.exchanges.Get(name)
+		if err != nil {
+			continue
+		}
+		if selector, ok := connector.(exchange.MarketSelector); ok {
+			selector.SelectMarkets(userID)
+		}
+	}
 }
 
 // =============================================================================
@@ -114,95 +163,610 @@ func (a *App) ForgotPassword(req auth.ForgotPasswordRequest) error {
 }
 
 // =============================================================================
-// Bybit integration methods
+// Exchange integration methods
 // =============================================================================
 
-// FetchSpotHoldings fetches spot holdings for a user
-func (a *App) FetchSpotHoldings(userId string) ([]bybit.Holding, error) {
-	return a.bybitService.FetchSpotHoldings(userId)
+// ListExchanges returns the names of all registered exchanges
+func (a *App) ListExchanges() []string {
+	return a.exchanges.Names()
+}
+
+// FetchSpotHoldings fetches spot holdings for a user from the default exchange
+func (a *App) FetchSpotHoldings(userId string) ([]exchange.Holding, error) {
+	return a.FetchExchangeHoldings(defaultExchange, userId)
+}
+
+// FetchExchangeHoldings fetches spot holdings for a user from the named exchange
+func (a *App) FetchExchangeHoldings(exchangeName string, userId string) ([]exchange.Holding, error) {
+	connector, err := a.exchanges.Get(exchangeName)
+	if err != nil {
+		return nil, err
+	}
+	return connector.GetHoldings(a.requestCtx(), userId)
 }
 
 // GetAssetBalance retrieves balance for a specific coin for the user
-func (a *App) GetAssetBalance(userID string, coin string) (*bybit.CoinBalance, error) {
-	return a.bybitService.GetAssetBalance(userID, coin)
+func (a *App) GetAssetBalance(userID string, coin string) (*exchange.CoinBalance, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	return connector.GetBalance(a.requestCtx(), userID, coin)
 }
 
 // GetCoinIconURLs gets coin icon URLs
-func (a *App) GetCoinIconURLs(coins []string) ([]bybit.IconEntry, error) {
-	return a.bybitService.GetCoinIconURLs(coins)
+func (a *App) GetCoinIconURLs(coins []string) ([]exchange.IconEntry, error) {
+	icons, err := a.iconProvider()
+	if err != nil {
+		return nil, err
+	}
+	return icons.GetCoinIconURLs(coins)
 }
 
 // PrefetchCoinIcons prefetches coin icons for caching
 func (a *App) PrefetchCoinIcons(coins []string) {
-	a.bybitService.PrefetchCoinIcons(coins)
+	if icons, err := a.iconProvider(); err == nil {
+		icons.PrefetchCoinIcons(coins)
+	}
+}
+
+// iconProvider returns the default exchange if it can resolve coin icons
+func (a *App) iconProvider() (exchange.IconProvider, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	icons, ok := connector.(exchange.IconProvider)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q does not provide coin icons", connector.Name())
+	}
+	return icons, nil
+}
+
+// requestCtx returns the runtime context, falling back to a background context before startup
+func (a *App) requestCtx() context.Context {
+	if a.ctx != nil {
+		return a.ctx
+	}
+	return context.Background()
 }
 
 // =============================================================================
 // Price streaming methods
 // =============================================================================
 
-// StartPriceStream starts streaming prices for a symbol
+// maxPriceUpdatesPerSecond caps price-update events per stream
+const maxPriceUpdatesPerSecond = 4
+
+const priceEmitInterval = time.Second / maxPriceUpdatesPerSecond
+
+// priceStream is an active price subscription and the event it is emitted under
+type priceStream struct {
+	pair      exchange.Pair
+	ch        chan exchange.PriceData
+	eventName string
+	// legacyCoin is set for coin-only streams, whose payload symbol is the coin
+	legacyCoin string
+}
+
+// StartPriceStream starts streaming prices for a coin paired with its default
+// quote and emits them as price-update-<coin>. Kept for the coin-only frontend;
+// prefer StartPairStream or StartSymbolStream.
 func (a *App) StartPriceStream(symbol string) error {
+	coin := strings.ToLower(symbol)
+	return a.startStream(coin, symbol, fmt.Sprintf("price-update-%s", coin), coin)
+}
+
+// StopPriceStream stops a stream started with StartPriceStream
+func (a *App) StopPriceStream(symbol string) {
+	a.stopStream(strings.ToLower(symbol))
+}
+
+// StartPairStream starts streaming prices for base/quote, emitted as price-update-<base>-<quote>
+func (a *App) StartPairStream(base string, quote string) error {
+	key := pairKey(base, quote)
+	return a.startStream(key, base+"/"+quote, "price-update-"+key, "")
+}
+
+// StopPairStream stops a stream started with StartPairStream
+func (a *App) StopPairStream(base string, quote string) {
+	a.stopStream(pairKey(base, quote))
+}
+
+// StartSymbolStream starts streaming prices for a full instrument symbol such as
+// "ETHBTC". Events are emitted as price-update-<base>-<quote>; the resolved pair
+// is returned so the frontend knows which event to listen to.
+func (a *App) StartSymbolStream(symbol string) (*exchange.Pair, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.StartPairStream(pair.Base, pair.Quote); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// StopSymbolStream stops a stream started with StartSymbolStream
+func (a *App) StopSymbolStream(symbol string) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return
+	}
+	if pair, err := connector.ResolvePair(a.requestCtx(), symbol); err == nil {
+		a.StopPairStream(pair.Base, pair.Quote)
+	}
+}
+
+// pairKey is the lowercase base-quote key used for pair streams and their events
+func pairKey(base, quote string) string {
+	return strings.ToLower(base) + "-" + strings.ToLower(quote)
+}
+
+// startStream resolves symbol, subscribes and starts forwarding updates under key
+func (a *App) startStream(key, symbol, eventName, legacyCoin string) error {
 	a.priceMutex.Lock()
 	defer a.priceMutex.Unlock()
 
 	// Check if already subscribed
-	if _, exists := a.priceSubscriptions[symbol]; exists {
+	if _, exists := a.priceSubscriptions[key]; exists {
 		return nil
 	}
 
-	// Subscribe to price updates
-	priceChan, err := a.bybitService.SubscribeToPrice(symbol)
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
 	if err != nil {
 		return err
 	}
 
-	// Store the channel
-	a.priceSubscriptions[symbol] = priceChan
+	// Coalesce so a paused reader only ever sees the latest price
+	priceChan, err := connector.SubscribePair(*pair, exchange.SubscribeOptions{Policy: exchange.PolicyCoalesce})
+	if err != nil {
+		return err
+	}
 
-	// Start goroutine to handle price updates for this symbol
-	go a.handlePriceUpdates(symbol, priceChan)
+	stream := &priceStream{pair: *pair, ch: priceChan, eventName: eventName, legacyCoin: legacyCoin}
+	a.priceSubscriptions[key] = stream
+
+	// Start goroutine to handle price updates for this stream
+	go a.handlePriceUpdates(stream)
 
 	return nil
 }
 
-// StopPriceStream stops streaming prices for a symbol
-func (a *App) StopPriceStream(symbol string) {
+// stopStream unsubscribes the stream registered under key
+func (a *App) stopStream(key string) {
 	a.priceMutex.Lock()
 	defer a.priceMutex.Unlock()
 
-	if priceChan, exists := a.priceSubscriptions[symbol]; exists {
-		a.bybitService.UnsubscribeFromPrice(symbol, priceChan)
-		delete(a.priceSubscriptions, symbol)
+	if stream, exists := a.priceSubscriptions[key]; exists {
+		if connector, err := a.exchanges.Get(defaultExchange); err == nil {
+			connector.UnsubscribePair(stream.pair, stream.ch)
+		}
+		delete(a.priceSubscriptions, key)
 	}
 }
 
 // GetCurrentPrice gets the current price for a symbol (one-time request)
 func (a *App) GetCurrentPrice(symbol string) (string, error) {
-	return a.bybitService.GetCurrentPrice(symbol)
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return "", err
+	}
+	ticker, err := connector.GetTicker(a.requestCtx(), symbol)
+	if err != nil {
+		return "", err
+	}
+	return ticker.LastPrice, nil
 }
 
-// handlePriceUpdates processes incoming price updates and emits them to frontend
-func (a *App) handlePriceUpdates(symbol string, priceChan chan bybit.PriceData) {
-	for priceUpdate := range priceChan {
-		// Use the original symbol parameter to ensure consistency
-		// between frontend listener and backend emitter
-		coinSymbol := strings.ToLower(symbol)
+// GetTicker gets the full ticker with 24h statistics for a symbol (one-time request)
+func (a *App) GetTicker(symbol string) (*exchange.Ticker, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	return connector.GetTicker(a.requestCtx(), symbol)
+}
 
+// handlePriceUpdates processes incoming price updates and emits them to frontend.
+// After each event it pauses for priceEmitInterval; the coalescing subscription
+// keeps only the newest price meanwhile, so the UI gets the latest value at most
+// maxPriceUpdatesPerSecond times per second.
+func (a *App) handlePriceUpdates(stream *priceStream) {
+	for priceUpdate := range stream.ch {
 		priceValue := priceUpdate.Price
 		if priceValue == "" {
 			continue
 		}
 
-		// Emit event to frontend using the original symbol parameter
-		eventName := fmt.Sprintf("price-update-%s", coinSymbol)
+		// Coin-only listeners expect the coin they subscribed with as symbol
+		symbol := stream.pair.Symbol
+		if stream.legacyCoin != "" {
+			symbol = stream.legacyCoin
+		}
 		eventData := map[string]interface{}{
-			"symbol": coinSymbol,
+			"symbol": symbol,
+			"base":   stream.pair.Base,
+			"quote":  stream.pair.Quote,
 			"price":  priceValue,
+			"time":   priceUpdate.Time,
+			"ticker": priceUpdate.Ticker,
+		}
+
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, stream.eventName, eventData)
+		}
+		time.Sleep(priceEmitInterval)
+	}
+}
+
+// GetPriceStreamStats returns delivery and drop counters of every price
+// subscription, for diagnostics
+func (a *App) GetPriceStreamStats() ([]exchange.SubscriberStats, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	stats, ok := connector.(exchange.PriceStatsProvider)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q does not report price stream stats", connector.Name())
+	}
+	return stats.PriceStreamStats(), nil
+}
+
+// GetTradeStreamStats returns delivery and drop counters of every public
+// trade subscription, for diagnostics
+func (a *App) GetTradeStreamStats() ([]exchange.SubscriberStats, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, err
+	}
+	stats, ok := connector.(exchange.TradeStatsProvider)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q does not report trade stream stats", connector.Name())
+	}
+	return stats.TradeStreamStats(), nil
+}
+
+// =============================================================================
+// Order book streaming methods
+// =============================================================================
+
+// orderBookEmitInterval caps order book events to a rate the UI can render
+const orderBookEmitInterval = 250 * time.Millisecond
+
+// bookStream is an active order book subscription
+type bookStream struct {
+	pair      exchange.Pair
+	depth     int
+	ch        chan exchange.OrderBook
+	eventName string
+}
+
+// StartOrderBookStream starts streaming the L2 book of a symbol, coin or pair at
+// the given depth (1, 50 or 200). Books are emitted as orderbook-update-<base>-<quote>
+// at most every 250ms; the resolved pair is returned so the frontend knows which
+// event to listen to.
+func (a *App) StartOrderBookStream(symbol string, depth int) (*exchange.Pair, error) {
+	streamer, connector, err := a.orderBookStreamer()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	a.bookMutex.Lock()
+	defer a.bookMutex.Unlock()
+
+	key := bookKey(*pair, depth)
+	if _, exists := a.bookSubscriptions[key]; exists {
+		return pair, nil
+	}
+
+	ch, err := streamer.SubscribeOrderBook(*pair, depth)
+	if err != nil {
+		return nil, err
+	}
+	stream := &bookStream{
+		pair:      *pair,
+		depth:     depth,
+		ch:        ch,
+		eventName: "orderbook-update-" + pairKey(pair.Base, pair.Quote),
+	}
+	a.bookSubscriptions[key] = stream
+
+	go a.handleOrderBookUpdates(stream)
+
+	return pair, nil
+}
+
+// StopOrderBookStream stops a stream started with StartOrderBookStream
+func (a *App) StopOrderBookStream(symbol string, depth int) {
+	streamer, connector, err := a.orderBookStreamer()
+	if err != nil {
+		return
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return
+	}
+
+	a.bookMutex.Lock()
+	defer a.bookMutex.Unlock()
+
+	key := bookKey(*pair, depth)
+	if stream, exists := a.bookSubscriptions[key]; exists {
+		streamer.UnsubscribeOrderBook(stream.pair, stream.depth, stream.ch)
+		delete(a.bookSubscriptions, key)
+	}
+}
+
+// GetOrderBook gets the order book of a symbol at the given depth (one-time request)
+func (a *App) GetOrderBook(symbol string, depth int) (*exchange.OrderBook, error) {
+	streamer, connector, err := a.orderBookStreamer()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return nil, err
+	}
+	return streamer.GetOrderBook(a.requestCtx(), *pair, depth)
+}
+
+// orderBookStreamer returns the default exchange if it can stream order books
+func (a *App) orderBookStreamer() (exchange.OrderBookStreamer, exchange.Connector, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamer, ok := connector.(exchange.OrderBookStreamer)
+	if !ok {
+		return nil, nil, fmt.Errorf("exchange %q does not stream order books", connector.Name())
+	}
+	return streamer, connector, nil
+}
+
+// bookKey identifies an order book stream by pair and depth
+func bookKey(pair exchange.Pair, depth int) string {
+	return fmt.Sprintf("%s:%d", pairKey(pair.Base, pair.Quote), depth)
+}
+
+// handleOrderBookUpdates emits the latest book at most once per orderBookEmitInterval
+func (a *App) handleOrderBookUpdates(stream *bookStream) {
+	ticker := time.NewTicker(orderBookEmitInterval)
+	defer ticker.Stop()
+
+	var latest exchange.OrderBook
+	dirty := false
+	for {
+		select {
+		case book, ok := <-stream.ch:
+			if !ok {
+				return
+			}
+			latest = book
+			dirty = true
+		case <-ticker.C:
+			if !dirty {
+				continue
+			}
+			dirty = false
+			if a.ctx != nil {
+				runtime.EventsEmit(a.ctx, stream.eventName, latest)
+			}
+		}
+	}
+}
+
+// =============================================================================
+// Trade tape streaming methods
+// =============================================================================
+
+const (
+	// tradeEmitInterval is how often buffered trades are emitted as one event
+	tradeEmitInterval = 250 * time.Millisecond
+	// maxTradeBatch caps a single event; older trades of a burst are dropped
+	maxTradeBatch = 200
+)
+
+// tradeStream is an active public trade subscription
+type tradeStream struct {
+	pair      exchange.Pair
+	ch        chan exchange.Trade
+	eventName string
+}
+
+// StartTradeStream starts streaming public trades of a symbol, coin or pair.
+// Trades are batched and emitted as trades-update-<base>-<quote> with an array
+// payload, oldest first; the resolved pair is returned so the frontend knows
+// which event to listen to.
+func (a *App) StartTradeStream(symbol string) (*exchange.Pair, error) {
+	streamer, connector, err := a.tradeStreamer()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	a.tradeMutex.Lock()
+	defer a.tradeMutex.Unlock()
+
+	key := pairKey(pair.Base, pair.Quote)
+	if _, exists := a.tradeSubscriptions[key]; exists {
+		return pair, nil
+	}
+
+	ch, err := streamer.SubscribeTrades(*pair)
+	if err != nil {
+		return nil, err
+	}
+	stream := &tradeStream{pair: *pair, ch: ch, eventName: "trades-update-" + key}
+	a.tradeSubscriptions[key] = stream
+
+	go a.handleTradeUpdates(stream)
+
+	return pair, nil
+}
+
+// StopTradeStream stops a stream started with StartTradeStream
+func (a *App) StopTradeStream(symbol string) {
+	streamer, connector, err := a.tradeStreamer()
+	if err != nil {
+		return
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return
+	}
+
+	a.tradeMutex.Lock()
+	defer a.tradeMutex.Unlock()
+
+	key := pairKey(pair.Base, pair.Quote)
+	if stream, exists := a.tradeSubscriptions[key]; exists {
+		streamer.UnsubscribeTrades(stream.pair, stream.ch)
+		delete(a.tradeSubscriptions, key)
+	}
+}
+
+// tradeStreamer returns the default exchange if it can stream public trades
+func (a *App) tradeStreamer() (exchange.TradeStreamer, exchange.Connector, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamer, ok := connector.(exchange.TradeStreamer)
+	if !ok {
+		return nil, nil, fmt.Errorf("exchange %q does not stream trades", connector.Name())
+	}
+	return streamer, connector, nil
+}
+
+// handleTradeUpdates buffers trades and emits them in batches every tradeEmitInterval
+func (a *App) handleTradeUpdates(stream *tradeStream) {
+	ticker := time.NewTicker(tradeEmitInterval)
+	defer ticker.Stop()
+
+	var batch []exchange.Trade
+	for {
+		select {
+		case trade, ok := <-stream.ch:
+			if !ok {
+				return
+			}
+			batch = append(batch, trade)
+			if len(batch) > maxTradeBatch {
+				batch = batch[len(batch)-maxTradeBatch:]
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if a.ctx != nil {
+				runtime.EventsEmit(a.ctx, stream.eventName, batch)
+			}
+			batch = nil
 		}
+	}
+}
+
+// =============================================================================
+// Kline streaming methods
+// =============================================================================
+
+// klineStream is an active candlestick subscription
+type klineStream struct {
+	pair      exchange.Pair
+	interval  string
+	ch        chan exchange.Candle
+	eventName string
+}
+
+// StartKlineStream starts streaming candles of a symbol, coin or pair. Updates
+// are emitted as kline-update-<base>-<quote>-<interval>, where interval is the
+// value passed in; the candle's confirmed flag marks a closed interval.
+func (a *App) StartKlineStream(symbol string, interval string) (*exchange.Pair, error) {
+	provider, connector, err := a.klineProvider()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	a.klineMutex.Lock()
+	defer a.klineMutex.Unlock()
+
+	key := pairKey(pair.Base, pair.Quote) + "-" + interval
+	if _, exists := a.klineSubscriptions[key]; exists {
+		return pair, nil
+	}
+
+	ch, err := provider.SubscribeKlines(*pair, interval)
+	if err != nil {
+		return nil, err
+	}
+	stream := &klineStream{pair: *pair, interval: interval, ch: ch, eventName: "kline-update-" + key}
+	a.klineSubscriptions[key] = stream
+
+	go a.handleKlineUpdates(stream)
+
+	return pair, nil
+}
+
+// StopKlineStream stops a stream started with StartKlineStream
+func (a *App) StopKlineStream(symbol string, interval string) {
+	provider, connector, err := a.klineProvider()
+	if err != nil {
+		return
+	}
+	pair, err := connector.ResolvePair(a.requestCtx(), symbol)
+	if err != nil {
+		return
+	}
+
+	a.klineMutex.Lock()
+	defer a.klineMutex.Unlock()
+
+	key := pairKey(pair.Base, pair.Quote) + "-" + interval
+	if stream, exists := a.klineSubscriptions[key]; exists {
+		provider.UnsubscribeKlines(stream.pair, stream.interval, stream.ch)
+		delete(a.klineSubscriptions, key)
+	}
+}
+
+// klineProvider returns the default exchange if it serves candlestick data
+func (a *App) klineProvider() (exchange.KlineProvider, exchange.Connector, error) {
+	connector, err := a.exchanges.Get(defaultExchange)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider, ok := connector.(exchange.KlineProvider)
+	if !ok {
+		return nil, nil, fmt.Errorf("exchange %q does not provide klines", connector.Name())
+	}
+	return provider, connector, nil
+}
 
+// handleKlineUpdates emits every candle update to the frontend
+func (a *App) handleKlineUpdates(stream *klineStream) {
+	for candle := range stream.ch {
 		if a.ctx != nil {
-			runtime.EventsEmit(a.ctx, eventName, eventData)
+			runtime.EventsEmit(a.ctx, stream.eventName, candle)
 		}
 	}
 }
@@ -3,14 +3,19 @@ package bybit
 import (
 	"coin-control/backend/database"
 	"context"
+	"fmt"
 	"time"
 )
 
 type Bybit struct {
-	ID        string    `json:"id"`
-	ApiKey    string    `json:"apiKey"`
-	ApiSecret string    `json:"apiSecret"`
-	UserId    string    `json:"userId"`
+	ID        string `json:"id"`
+	ApiKey    string `json:"apiKey"`
+	ApiSecret string `json:"apiSecret"`
+	UserId    string `json:"userId"`
+	// Environment is one of mainnet, testnet, demo or custom
+	Environment string `json:"environment"`
+	// BaseURL is the REST base used when Environment is custom
+	BaseURL   string    `json:"baseUrl"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -42,26 +47,74 @@ func (s *BybitService) PrefetchCoinIcons(coins []string) {
 	go s.prefetchCoinIcons(coins)
 }
 
-// GetCurrentPrice gets current price for a symbol via REST API
-func (s *BybitService) GetCurrentPrice(symbol string) (string, error) {
-	return getCurrentPrice(symbol)
+// GetCurrentPrice gets current price for a symbol via REST API, from the
+// environment of the user's credentials
+func (s *BybitService) GetCurrentPrice(userId string, symbol string) (string, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return "", err
+	}
+	ticker, err := getTicker(context.Background(), ep.rest, symbol)
+	if err != nil {
+		return "", err
+	}
+	return ticker.LastPrice, nil
 }
 
-// Subscribe to real-time price updates for a symbol
-func (s *BybitService) SubscribeToPrice(symbol string) (chan PriceData, error) {
-	wsManager := GetWebSocketManager()
-	return wsManager.Subscribe(symbol)
+// GetInstruments returns all spot instruments listed in the user's environment
+// with their trading rules
+func (s *BybitService) GetInstruments(userId string) ([]Instrument, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return nil, err
+	}
+	return instrumentsFor(ep.rest).list(context.Background())
+}
+
+// ResolveSymbol maps a coin ("eth") or full symbol ("ETHBTC") to a spot
+// instrument listed in the user's environment
+func (s *BybitService) ResolveSymbol(userId string, coinOrSymbol string) (*Instrument, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return nil, err
+	}
+	return instrumentsFor(ep.rest).resolve(context.Background(), coinOrSymbol)
+}
+
+// GetClockStatus reports the local clock offset against every Bybit host in use
+func (s *BybitService) GetClockStatus() []ClockStatus {
+	return getClockStatuses()
+}
+
+// Subscribe to real-time price updates for a symbol in the user's environment
+func (s *BybitService) SubscribeToPrice(userId string, symbol string) (chan PriceData, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return nil, err
+	}
+	return webSocketManagerFor(ep).Subscribe(symbol)
 }
 
 // Unsubscribe from price updates
-func (s *BybitService) UnsubscribeFromPrice(symbol string, ch chan PriceData) {
-	wsManager := GetWebSocketManager()
-	wsManager.Unsubscribe(symbol, ch)
+func (s *BybitService) UnsubscribeFromPrice(userId string, symbol string, ch chan PriceData) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return
+	}
+	webSocketManagerFor(ep).Unsubscribe(symbol, ch)
 }
 
 func (s *BybitService) CreateBybit(bybit Bybit) (string, error) {
 	ctx := context.Background()
 
+	env, err := parseEnvironment(bybit.Environment)
+	if err != nil {
+		return "", err
+	}
+	if _, err := resolveEndpoints(env, bybit.BaseURL); err != nil {
+		return "", err
+	}
+
 	// encrypt secret on write
 	encSecret, err := encryptString(bybit.ApiSecret)
 	if err != nil {
@@ -69,12 +122,12 @@ func (s *BybitService) CreateBybit(bybit Bybit) (string, error) {
 	}
 
 	query := `
-		INSERT INTO bybit (api_key, api_secret, user_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO bybit (api_key, api_secret, user_id, environment, base_url)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
 	`
 	var newID string
-	err = database.DB.QueryRow(ctx, query, bybit.ApiKey, encSecret, bybit.UserId).Scan(&newID)
+	err = database.DB.QueryRow(ctx, query, bybit.ApiKey, encSecret, bybit.UserId, string(env), bybit.BaseURL).Scan(&newID)
 	if err != nil {
 		return "", err
 	}
@@ -105,16 +158,66 @@ func (s *BybitService) UpsertBybit(bybitApi string, bybitApiSecret string, userI
 	return nil
 }
 
+// SetBybitEnvironment selects the Bybit deployment used for the user's credentials.
+// baseURL is only used when environment is "custom".
+func (s *BybitService) SetBybitEnvironment(userId string, environment string, baseURL string) error {
+	ctx := context.Background()
+
+	env, err := parseEnvironment(environment)
+	if err != nil {
+		return err
+	}
+	if env != EnvCustom {
+		baseURL = ""
+	}
+	if _, err := resolveEndpoints(env, baseURL); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE bybit
+		SET environment = $1, base_url = $2, updated_at = $3
+		WHERE user_id = $4
+	`
+	result, err := database.DB.Exec(ctx, query, string(env), baseURL, time.Now(), userId)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("bybit credentials not found")
+	}
+	return nil
+}
+
+// listBybitUserIDs returns every user that has Bybit credentials
+func listBybitUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := database.DB.Query(ctx, `SELECT user_id FROM bybit ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (s *BybitService) GetBybitByUserId(userId string) (*Bybit, error) {
 	ctx := context.Background()
 
 	query := `
-		SELECT id, api_key, api_secret, user_id, created_at, updated_at
+		SELECT id, api_key, api_secret, user_id, environment, base_url, created_at, updated_at
 		FROM bybit
 		WHERE user_id = $1
 	`
 	var bybit Bybit
-	err := database.DB.QueryRow(ctx, query, userId).Scan(&bybit.ID, &bybit.ApiKey, &bybit.ApiSecret, &bybit.UserId, &bybit.CreatedAt, &bybit.UpdatedAt)
+	err := database.DB.QueryRow(ctx, query, userId).Scan(&bybit.ID, &bybit.ApiKey, &bybit.ApiSecret, &bybit.UserId, &bybit.Environment, &bybit.BaseURL, &bybit.CreatedAt, &bybit.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -129,3 +232,155 @@ func (s *BybitService) GetBybitByUserId(userId string) (*Bybit, error) {
 func (s *BybitService) GetAssetBalance(userId string, coin string) (*CoinBalance, error) {
 	return s.getAssetBalance(context.Background(), userId, coin)
 }
+
+// PlaceOrder places a spot market or limit order
+func (s *BybitService) PlaceOrder(userId string, req OrderRequest) (*OrderResult, error) {
+	return s.placeOrder(context.Background(), userId, req)
+}
+
+// AmendOrder changes the quantity and/or price of an open spot order
+func (s *BybitService) AmendOrder(userId string, req AmendOrderRequest) (*OrderResult, error) {
+	return s.amendOrder(context.Background(), userId, req)
+}
+
+// CancelOrder cancels an open spot order by orderId or orderLinkId
+func (s *BybitService) CancelOrder(userId string, symbol string, orderId string, orderLinkId string) (*OrderResult, error) {
+	return s.cancelOrder(context.Background(), userId, symbol, orderId, orderLinkId)
+}
+
+// CancelAllOrders cancels all open spot orders, or only those for symbol if set
+func (s *BybitService) CancelAllOrders(userId string, symbol string) ([]OrderResult, error) {
+	return s.cancelAllOrders(context.Background(), userId, symbol)
+}
+
+// GetOpenOrders lists the user's open spot orders
+func (s *BybitService) GetOpenOrders(userId string, filter OrderFilter) ([]Order, error) {
+	return s.getOpenOrders(context.Background(), userId, filter)
+}
+
+// GetOrderHistory lists the user's spot order history
+func (s *BybitService) GetOrderHistory(userId string, filter OrderFilter) ([]Order, error) {
+	return s.getOrderHistory(context.Background(), userId, filter)
+}
+
+// GetExecutions fetches the user's spot fills in [startTime, endTime] (ms) from
+// Bybit and stores them in the local trade ledger
+func (s *BybitService) GetExecutions(userId string, symbol string, startTime int64, endTime int64) ([]Execution, error) {
+	return s.getExecutions(context.Background(), userId, symbol, startTime, endTime)
+}
+
+// GetStoredExecutions reads the user's fills from the local trade ledger
+func (s *BybitService) GetStoredExecutions(userId string, symbol string, startTime int64, endTime int64) ([]Execution, error) {
+	return getStoredExecutions(context.Background(), userId, symbol, startTime, endTime)
+}
+
+// GetKlines fetches candles of a spot symbol in [start, end] (ms) from the
+// user's environment, oldest first. Interval is a Bybit code ("1", "60", "D")
+// or an alias such as "1h".
+func (s *BybitService) GetKlines(userId string, symbol string, interval string, start int64, end int64) ([]Candle, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return nil, err
+	}
+	return getKlines(context.Background(), ep.rest, symbol, interval, start, end)
+}
+
+// GetCandles serves confirmed candles of a spot symbol in [start, end] (ms)
+// from the local store of the user's environment, fetching missing ones from
+// Bybit. Long gaps are filled in the background and announced with the
+// candles-backfilled event.
+func (s *BybitService) GetCandles(userId string, symbol string, interval string, start int64, end int64) ([]Candle, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return nil, err
+	}
+	return getCandles(context.Background(), ep.rest, symbol, interval, start, end)
+}
+
+// BackfillCandles queues a background backfill of [start, end] (ms) in the
+// user's environment, e.g. to preload months of history
+func (s *BybitService) BackfillCandles(userId string, symbol string, interval string, start int64, end int64) error {
+	if taskQueue == nil {
+		return fmt.Errorf("task queue is not running")
+	}
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return err
+	}
+	code, err := normalizeInterval(interval)
+	if err != nil {
+		return err
+	}
+	inst, err := instrumentsFor(ep.rest).lookup(context.Background(), symbol)
+	if err != nil {
+		return err
+	}
+	if end <= 0 {
+		end = time.Now().UnixMilli()
+	}
+	return enqueueCandleBackfill(ep.rest, inst.Symbol, code, start, end)
+}
+
+// StartAccountStream opens the user's private WebSocket. Balance, order and fill
+// changes are then pushed as wallet-update-<userId>, order-update-<userId> and
+// execution-update-<userId> instead of being polled. The connection state is
+// pushed as account-stream-<userId>.
+func (s *BybitService) StartAccountStream(userId string) error {
+	return s.startPrivateStream(userId)
+}
+
+// StopAccountStream closes the user's private WebSocket
+func (s *BybitService) StopAccountStream(userId string) {
+	stopPrivateStream(userId)
+}
+
+// GetStreamStatus returns the health of the public market data WebSocket of
+// the user's environment. Later changes are pushed as the ws-status event.
+func (s *BybitService) GetStreamStatus(userId string) (WSStatus, error) {
+	ep, err := s.userEndpoints(userId)
+	if err != nil {
+		return WSStatus{}, err
+	}
+	return webSocketManagerFor(ep).Status(), nil
+}
+
+// GetPortfolioValuation values the user's spot holdings in USDT, USD, EUR or BTC,
+// with per-coin value and allocation. Coins without a direct pair are priced
+// through an intermediate asset such as USDT or BTC.
+func (s *BybitService) GetPortfolioValuation(userId string, quote string) (*PortfolioValuation, error) {
+	return s.getPortfolioValuation(context.Background(), userId, quote)
+}
+
+// StartPortfolioStream keeps the user's portfolio valued in quote from live
+// prices and emits it as the portfolio-update event (at most once a second).
+// Symbols are added and dropped as holdings change.
+func (s *BybitService) StartPortfolioStream(userId string, quote string) error {
+	return s.startPortfolioStream(userId, quote)
+}
+
+// StopPortfolioStream stops the user's portfolio stream
+func (s *BybitService) StopPortfolioStream(userId string) {
+	stopPortfolioStream(userId)
+}
+
+// GetPortfolioHistory returns the user's net worth over a range ("24h", "7d",
+// "30d", "90d", "1y", "all") at a resolution ("raw", "1h", "4h", "1d", "1w";
+// empty picks one for the range), in USDT, USD, EUR and BTC
+func (s *BybitService) GetPortfolioHistory(userId string, rangeName string, resolution string) ([]PortfolioPoint, error) {
+	return getPortfolioHistory(context.Background(), userId, rangeName, resolution)
+}
+
+// GetPnl returns realized and unrealized P&L per coin and in total, replaying
+// the user's spot fills with a cost basis method ("fifo", "lifo" or
+// "average") and valuing them in quote (USDT, USD, EUR or BTC). Fills are
+// synced from Bybit first; a long sync such as the first one runs in the
+// background, the report is marked syncing and progress is emitted as
+// "executions-sync" events.
+func (s *BybitService) GetPnl(userId string, method string, quote string) (*PnlReport, error) {
+	return s.getPnl(context.Background(), userId, method, quote)
+}
+
+// GetCoinPnl returns the P&L of a single coin, see GetPnl
+func (s *BybitService) GetCoinPnl(userId string, coin string, method string, quote string) (*CoinPnl, error) {
+	return s.getCoinPnl(context.Background(), userId, coin, method, quote)
+}
@@ -0,0 +1,339 @@
+package bybit
+
+import (
+	"coin-control/backend/database"
+	"coin-control/backend/queue"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// TaskBackfillCandles fills gaps of the local candle store from /v5/market/kline
+	TaskBackfillCandles = "bybit:backfill_candles"
+
+	// candleSyncLimit is the largest gap (in candles) filled while a chart
+	// query waits; larger gaps are handed to the backfill task
+	candleSyncLimit = klinePageSize
+
+	// backfillChunk is the most candles a single backfill task covers, well
+	// below the maxPages pagination cap of one kline fetch
+	backfillChunk = 50 * klinePageSize
+
+	// candlesBackfilledEvent tells the frontend to re-query a chart range
+	candlesBackfilledEvent = "candles-backfilled"
+)
+
+// taskQueue is set by RegisterTasks; without it large gaps are filled inline
+var taskQueue *queue.Queue
+
+// RegisterTasks registers the Bybit task handlers and schedules, and keeps q
+// for enqueueing. It must be called before q.Start and q.StartScheduler.
+func RegisterTasks(q *queue.Queue, service *BybitService) {
+	taskQueue = q
+	q.HandleFunc(TaskBackfillCandles, handleBackfillCandles)
+	q.HandleFunc(TaskSnapshotPortfolios, service.handleSnapshotPortfolios)
+	q.HandleFunc(TaskSyncExecutions, service.handleSyncExecutions)
+	q.Schedule(TaskSnapshotPortfolios, snapshotInterval())
+}
+
+// backfillPayload is the payload of TaskBackfillCandles
+type backfillPayload struct {
+	Host     string `json:"host"` // REST host, see database.Candle
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+}
+
+// candleGap is a run of missing candle open times [From, To] (ms)
+type candleGap struct {
+	From  int64
+	To    int64
+	Count int
+}
+
+// candleStart returns the open time of the candle containing ts. Candles are
+// aligned to UTC; weekly candles open on Monday, monthly on the 1st.
+func candleStart(ts int64, interval string) int64 {
+	t := time.UnixMilli(ts).UTC()
+	switch interval {
+	case "M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	case "W":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset).UnixMilli()
+	default:
+		step := klineIntervals[interval].Milliseconds()
+		return ts - ts%step
+	}
+}
+
+// findCandleGaps returns the missing confirmed candles of [start, end] given
+// the open times already stored and the ranges known to have no candles,
+// oldest first. now is the server time of the host the candles come from.
+func findCandleGaps(interval string, start, end, now int64, stored []time.Time, empty []database.CandleRange) []candleGap {
+	have := make(map[int64]bool, len(stored))
+	for _, t := range stored {
+		have[t.UnixMilli()] = true
+	}
+	known := func(ts int64) bool {
+		if have[ts] {
+			return true
+		}
+		for _, r := range empty {
+			if ts >= r.From.UnixMilli() && ts <= r.To.UnixMilli() {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Only closed candles are stored, so the range stops at the last one
+	var gaps []candleGap
+	var cur *candleGap
+	for ts := candleStart(start, interval); ts <= end; ts = intervalEnd(ts, interval) + 1 {
+		if intervalEnd(ts, interval) >= now {
+			break
+		}
+		if known(ts) {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			gaps = append(gaps, candleGap{From: ts})
+			cur = &gaps[len(gaps)-1]
+		}
+		cur.To = ts
+		cur.Count++
+	}
+	return gaps
+}
+
+// loadCandleCoverage returns what the local store knows about [start, end]:
+// the stored open times and the ranges Bybit has no candles for
+func loadCandleCoverage(ctx context.Context, host, symbol, interval string, start, end int64) ([]time.Time, []database.CandleRange, error) {
+	// The first candle may open before start
+	from, to := time.UnixMilli(candleStart(start, interval)), time.UnixMilli(end)
+	stored, err := database.GetCandleOpenTimes(ctx, host, symbol, interval, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	empty, err := database.GetCandleEmptyRanges(ctx, host, symbol, interval, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored, empty, nil
+}
+
+// fillCandleGaps downloads the missing candles and stores the confirmed ones.
+// Open times Bybit returns nothing for are recorded as empty so they are not
+// fetched again, but only inside the range the download covered.
+func fillCandleGaps(ctx context.Context, host, symbol, interval string, gaps []candleGap) error {
+	for _, gap := range gaps {
+		if gap.From <= 0 {
+			return fmt.Errorf("candle gap of %s starts at %d", symbol, gap.From)
+		}
+		candles, covered, err := fetchKlines(ctx, host, symbol, interval, gap.From, intervalEnd(gap.To, interval))
+		if err != nil {
+			return err
+		}
+		rows := make([]database.Candle, 0, len(candles))
+		got := make(map[int64]bool, len(candles))
+		for _, c := range candles {
+			if c.Confirmed {
+				rows = append(rows, toCandleRow(host, c))
+				got[c.Start] = true
+			}
+		}
+		if err := database.SaveCandles(ctx, rows); err != nil {
+			return err
+		}
+		if err := database.SaveCandleEmptyRanges(ctx, host, symbol, interval, emptyRanges(gap, interval, covered, got)); err != nil {
+			return err
+		}
+		if covered > gap.From {
+			return fmt.Errorf("candles of %s %s before %d were not fetched", symbol, interval, covered)
+		}
+	}
+	return nil
+}
+
+// emptyRanges returns the runs of open times in gap, from covered on, that
+// got has no candle for
+func emptyRanges(gap candleGap, interval string, covered int64, got map[int64]bool) []database.CandleRange {
+	var ranges []database.CandleRange
+	var cur *database.CandleRange
+	for ts := gap.From; ts <= gap.To; ts = intervalEnd(ts, interval) + 1 {
+		if ts < covered || got[ts] {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			ranges = append(ranges, database.CandleRange{From: time.UnixMilli(ts)})
+			cur = &ranges[len(ranges)-1]
+		}
+		cur.To = time.UnixMilli(ts)
+	}
+	return ranges
+}
+
+// getCandles serves [start, end] (ms) from the local store of the REST host
+// rest, filling gaps from it first. Gaps larger than candleSyncLimit are queued for backfill and the
+// locally available candles are returned; candles-backfilled is emitted once
+// the task finishes so the chart can re-query.
+func getCandles(ctx context.Context, rest, symbol, interval string, start, end int64) ([]Candle, error) {
+	code, err := normalizeInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := instrumentsFor(rest).lookup(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if end <= 0 {
+		end = time.Now().UnixMilli()
+	}
+	if err := checkCandleRange(start, end); err != nil {
+		return nil, err
+	}
+
+	stored, empty, err := loadCandleCoverage(ctx, rest, inst.Symbol, code, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	trackServerClock(rest)
+	if gaps := findCandleGaps(code, start, end, serverNow(rest), stored, empty); len(gaps) > 0 {
+		missing := 0
+		for _, gap := range gaps {
+			missing += gap.Count
+		}
+		if missing > candleSyncLimit && taskQueue != nil {
+			if err := enqueueCandleBackfill(rest, inst.Symbol, code, start, end); err != nil {
+				log.Printf("Failed to enqueue candle backfill for %s: %v", inst.Symbol, err)
+			}
+		} else if err := fillCandleGaps(ctx, rest, inst.Symbol, code, gaps); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := database.GetCandles(ctx, rest, inst.Symbol, code, time.UnixMilli(start), time.UnixMilli(end))
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]Candle, 0, len(rows))
+	for _, r := range rows {
+		candles = append(candles, fromCandleRow(r))
+	}
+	return candles, nil
+}
+
+// checkCandleRange rejects ranges without a start. Walking from the epoch
+// would mark everything before the first fetched page as empty.
+func checkCandleRange(start, end int64) error {
+	if start <= 0 {
+		return fmt.Errorf("candle start is required")
+	}
+	if start > end {
+		return fmt.Errorf("candle start %d is after end %d", start, end)
+	}
+	return nil
+}
+
+// enqueueCandleBackfill queues a backfill of [start, end], one task per
+// backfillChunk candles so each stays within the pagination cap and retries
+// only its own chunk. Identical requests are deduplicated while one is pending.
+func enqueueCandleBackfill(host, symbol, interval string, start, end int64) error {
+	if err := checkCandleRange(start, end); err != nil {
+		return err
+	}
+	for from := start; from <= end; {
+		to := end
+		if step := klineIntervals[interval].Milliseconds(); step > 0 && (end-from)/step >= backfillChunk {
+			to = from + backfillChunk*step - 1
+		}
+		payload, err := json.Marshal(backfillPayload{Host: host, Symbol: symbol, Interval: interval, Start: from, End: to})
+		if err != nil {
+			return err
+		}
+		task := asynq.NewTask(TaskBackfillCandles, payload)
+		err = taskQueue.Enqueue(task, asynq.MaxRetry(3), asynq.Unique(time.Hour))
+		if err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+			return err
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// handleBackfillCandles finds and fills the gaps of the requested range
+func handleBackfillCandles(ctx context.Context, t *asynq.Task) error {
+	var p backfillPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("bad %s payload: %v: %w", TaskBackfillCandles, err, asynq.SkipRetry)
+	}
+
+	if p.Host == "" {
+		// Queued before candles were keyed by host
+		p.Host = defaultEndpoints.rest
+	}
+	if err := checkCandleRange(p.Start, p.End); err != nil {
+		return fmt.Errorf("bad %s payload: %v: %w", TaskBackfillCandles, err, asynq.SkipRetry)
+	}
+
+	stored, empty, err := loadCandleCoverage(ctx, p.Host, p.Symbol, p.Interval, p.Start, p.End)
+	if err != nil {
+		return err
+	}
+	trackServerClock(p.Host)
+	gaps := findCandleGaps(p.Interval, p.Start, p.End, serverNow(p.Host), stored, empty)
+	if err := fillCandleGaps(ctx, p.Host, p.Symbol, p.Interval, gaps); err != nil {
+		return err
+	}
+
+	log.Printf("Backfilled %d candle gap(s) of %s %s", len(gaps), p.Symbol, p.Interval)
+	if rctx := getRuntimeCtx(); rctx != nil {
+		runtime.EventsEmit(rctx, candlesBackfilledEvent, p)
+	}
+	return nil
+}
+
+func toCandleRow(host string, c Candle) database.Candle {
+	return database.Candle{
+		Host:      host,
+		Symbol:    c.Symbol,
+		Interval:  c.Interval,
+		OpenTime:  time.UnixMilli(c.Start),
+		CloseTime: time.UnixMilli(c.End),
+		Open:      c.Open,
+		High:      c.High,
+		Low:       c.Low,
+		Close:     c.Close,
+		Volume:    c.Volume,
+		Turnover:  c.Turnover,
+	}
+}
+
+func fromCandleRow(r database.Candle) Candle {
+	return Candle{
+		Symbol:    r.Symbol,
+		Interval:  r.Interval,
+		Start:     r.OpenTime.UnixMilli(),
+		End:       r.CloseTime.UnixMilli(),
+		Open:      r.Open,
+		High:      r.High,
+		Low:       r.Low,
+		Close:     r.Close,
+		Volume:    r.Volume,
+		Turnover:  r.Turnover,
+		Confirmed: true,
+	}
+}
@@ -0,0 +1,197 @@
+package bybit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// recvWindow is how long (ms) Bybit accepts a signed request after its timestamp
+const recvWindow = "8000"
+
+// v5Envelope is the common response wrapper returned by every Bybit v5 endpoint
+type v5Envelope struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+	Time    int64           `json:"time"`
+}
+
+// v5Client performs signed requests against Bybit v5 private endpoints
+type v5Client struct {
+	apiKey  string
+	secret  string
+	baseURL string
+	http    *http.Client
+}
+
+// newPrivateClient builds a signed client from the user's stored credentials
+func (s *BybitService) newPrivateClient(userID string) (*v5Client, error) {
+	creds, err := s.GetBybitByUserId(userID)
+	if err != nil {
+		return nil, fmt.Errorf("bybit credentials not found: %w", err)
+	}
+	ep, err := creds.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	trackServerClock(ep.rest)
+	return &v5Client{
+		apiKey:  creds.ApiKey,
+		secret:  creds.ApiSecret,
+		baseURL: ep.rest,
+		http:    httpClient,
+	}, nil
+}
+
+// get sends a signed GET request and decodes the envelope result into out.
+// GETs are idempotent, so timeouts, 5xx and rate limit rejections are retried.
+func (c *v5Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	queryString := query.Encode()
+	return withRetry(ctx, func() error {
+		return c.do(ctx, http.MethodGet, path, queryString, nil, out)
+	})
+}
+
+// post sends a signed POST request with a JSON body and decodes the envelope result into out
+func (c *v5Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, path, "", payload, out)
+}
+
+// do sends the request, resyncing the server clock and retrying once if Bybit
+// rejects the timestamp
+func (c *v5Client) do(ctx context.Context, method, path, queryString string, body []byte, out interface{}) error {
+	err := c.doOnce(ctx, method, path, queryString, body, out)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsTimestampError() {
+		if syncErr := syncServerTime(ctx, c.baseURL); syncErr != nil {
+			dbg("server time sync failed: %v", syncErr)
+			return err
+		}
+		return c.doOnce(ctx, method, path, queryString, body, out)
+	}
+	return err
+}
+
+func (c *v5Client) doOnce(ctx context.Context, method, path, queryString string, body []byte, out interface{}) error {
+	reqURL := c.baseURL + path
+	if queryString != "" {
+		reqURL += "?" + queryString
+	}
+
+	// GET signs the query string, POST signs the raw JSON body
+	payload := queryString
+	var reader io.Reader
+	if body != nil {
+		payload = string(body)
+		reader = bytes.NewReader(body)
+	}
+
+	limiter := limiterFor(c.apiKey, path)
+	if err := limiter.wait(ctx); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(serverNow(c.baseURL), 10)
+	signature := signV5(c.apiKey, c.secret, timestamp, recvWindow, payload)
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-BAPI-API-KEY", c.apiKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
+	req.Header.Set("X-BAPI-SIGN", signature)
+	req.Header.Set("X-BAPI-SIGN-TYPE", "2")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	limiter.observe(resp.Header)
+
+	return decodeEnvelope(resp, path, out)
+}
+
+// maxPages bounds cursor pagination in case the exchange keeps returning cursors
+const maxPages = 200
+
+// cursorPage is the result shape shared by cursor-paginated v5 endpoints
+type cursorPage[T any] struct {
+	NextPageCursor string `json:"nextPageCursor"`
+	List           []T    `json:"list"`
+}
+
+// getAllPages follows nextPageCursor until it is exhausted or limit items were
+// collected. A limit of 0 collects everything.
+func getAllPages[T any](ctx context.Context, c *v5Client, path string, query url.Values, limit int) ([]T, error) {
+	var out []T
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	for page := 0; page < maxPages; page++ {
+		var result cursorPage[T]
+		if err := c.get(ctx, path, q, &result); err != nil {
+			return nil, err
+		}
+		out = append(out, result.List...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			return out, nil
+		}
+		q.Set("cursor", result.NextPageCursor)
+	}
+	return out, fmt.Errorf("bybit %s: pagination did not finish after %d pages", path, maxPages)
+}
+
+// decodeEnvelope reads a v5 response, converting non-zero retCodes into *APIError
+func decodeEnvelope(resp *http.Response, path string, out interface{}) error {
+	_, err := decodeEnvelopeTime(resp, path, out)
+	return err
+}
+
+// decodeEnvelopeTime is decodeEnvelope that also returns the envelope time (ms)
+func decodeEnvelopeTime(resp *http.Response, path string, out interface{}) (int64, error) {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var env v5Envelope
+	if jsonErr := json.Unmarshal(raw, &env); jsonErr != nil {
+		if resp.StatusCode >= 300 {
+			return 0, &APIError{RetMsg: string(raw), HTTPStatus: resp.StatusCode, Endpoint: path}
+		}
+		return 0, fmt.Errorf("failed to parse %s response: %w", path, jsonErr)
+	}
+
+	if env.RetCode != 0 || resp.StatusCode >= 300 {
+		return 0, &APIError{RetCode: env.RetCode, RetMsg: env.RetMsg, HTTPStatus: resp.StatusCode, Endpoint: path}
+	}
+
+	if out == nil || len(env.Result) == 0 {
+		return env.Time, nil
+	}
+	if err := json.Unmarshal(env.Result, out); err != nil {
+		return 0, fmt.Errorf("failed to parse %s result: %w", path, err)
+	}
+	return env.Time, nil
+}
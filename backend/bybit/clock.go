@@ -0,0 +1,161 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// clockSyncInterval is how often tracked hosts are asked for their time
+	clockSyncInterval = 10 * time.Minute
+	// clockDriftThreshold is the local clock offset at which the UI is warned
+	clockDriftThreshold = 2 * time.Second
+	// clockDriftEvent is emitted whenever a host crosses the drift threshold
+	clockDriftEvent = "bybit-clock-drift"
+)
+
+// ClockStatus describes the local clock offset against a Bybit host
+type ClockStatus struct {
+	BaseURL     string `json:"baseUrl"`
+	OffsetMs    int64  `json:"offsetMs"`
+	ThresholdMs int64  `json:"thresholdMs"`
+	Drifting    bool   `json:"drifting"`
+	SyncedAt    int64  `json:"syncedAt"`
+}
+
+var (
+	clockMu     sync.RWMutex
+	clockStates = map[string]*ClockStatus{} // keyed by REST base URL
+	clockOnce   sync.Once
+)
+
+// serverNow returns the current time in ms, corrected by the last known offset for baseURL
+func serverNow(baseURL string) int64 {
+	clockMu.RLock()
+	var offset int64
+	if st, ok := clockStates[baseURL]; ok {
+		offset = st.OffsetMs
+	}
+	clockMu.RUnlock()
+	return time.Now().UnixMilli() + offset
+}
+
+// trackServerClock registers baseURL for periodic time sync. The first
+// registration of a host syncs it immediately in the background.
+func trackServerClock(baseURL string) {
+	clockOnce.Do(func() {
+		go clockSyncLoop()
+	})
+
+	clockMu.Lock()
+	_, known := clockStates[baseURL]
+	if !known {
+		clockStates[baseURL] = &ClockStatus{BaseURL: baseURL, ThresholdMs: clockDriftThreshold.Milliseconds()}
+	}
+	clockMu.Unlock()
+
+	if !known {
+		go func() {
+			if err := syncServerTime(context.Background(), baseURL); err != nil {
+				dbg("initial server time sync for %s failed: %v", baseURL, err)
+			}
+		}()
+	}
+}
+
+// clockSyncLoop resyncs every tracked host on a fixed interval
+func clockSyncLoop() {
+	ticker := time.NewTicker(clockSyncInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		clockMu.RLock()
+		hosts := make([]string, 0, len(clockStates))
+		for baseURL := range clockStates {
+			hosts = append(hosts, baseURL)
+		}
+		clockMu.RUnlock()
+
+		for _, baseURL := range hosts {
+			if err := syncServerTime(context.Background(), baseURL); err != nil {
+				dbg("server time sync for %s failed: %v", baseURL, err)
+			}
+		}
+	}
+}
+
+// syncServerTime queries /v5/market/time and stores the offset for baseURL
+func syncServerTime(ctx context.Context, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v5/market/time", nil)
+	if err != nil {
+		return err
+	}
+
+	sent := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+
+	var result struct {
+		TimeNano string `json:"timeNano"`
+	}
+	if err := decodeEnvelope(resp, "/v5/market/time", &result); err != nil {
+		return err
+	}
+	nanos, err := strconv.ParseInt(result.TimeNano, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid server time %q: %w", result.TimeNano, err)
+	}
+
+	// Assume the server stamped the response halfway through the round trip
+	local := sent.Add(received.Sub(sent) / 2).UnixMilli()
+	offset := nanos/int64(time.Millisecond) - local
+	drifting := offset > clockDriftThreshold.Milliseconds() || -offset > clockDriftThreshold.Milliseconds()
+
+	clockMu.Lock()
+	st, ok := clockStates[baseURL]
+	if !ok {
+		st = &ClockStatus{BaseURL: baseURL, ThresholdMs: clockDriftThreshold.Milliseconds()}
+		clockStates[baseURL] = st
+	}
+	changed := st.Drifting != drifting
+	st.OffsetMs = offset
+	st.Drifting = drifting
+	st.SyncedAt = received.UnixMilli()
+	snapshot := *st
+	clockMu.Unlock()
+
+	dbg("bybit server time offset for %s: %dms", baseURL, offset)
+	if changed {
+		emitClockDrift(snapshot)
+	}
+	return nil
+}
+
+// emitClockDrift notifies the frontend that a host crossed the drift threshold
+func emitClockDrift(status ClockStatus) {
+	ctx := getRuntimeCtx()
+	if ctx == nil {
+		return
+	}
+	runtime.EventsEmit(ctx, clockDriftEvent, status)
+}
+
+// getClockStatuses returns the current offset of every tracked host
+func getClockStatuses() []ClockStatus {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	out := make([]ClockStatus, 0, len(clockStates))
+	for _, st := range clockStates {
+		out = append(out, *st)
+	}
+	return out
+}
@@ -1,10 +1,13 @@
 package bybit
 
 import (
+	"coin-control/backend/exchange"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -13,15 +16,7 @@ import (
 	"time"
 )
 
-type IconEntry struct {
-	Coin string `json:"coin"`
-	// Back-compat: keep iconUrl (will mirror darkUrl by default)
-	IconURL      string `json:"iconUrl,omitempty"`
-	DarkURL      string `json:"darkUrl,omitempty"`
-	LightURL     string `json:"lightUrl,omitempty"`
-	DarkDataURL  string `json:"darkDataUrl,omitempty"`
-	LightDataURL string `json:"lightDataUrl,omitempty"`
-}
+type IconEntry = exchange.IconEntry
 
 type iconCacheEntry struct {
 	url       string
@@ -183,14 +178,9 @@ func ensureIconIndex() error {
 // fetchIconIndexFromBriefList queries Bybit X-API brief symbol list and builds coin->iconURL index
 func fetchIconIndexFromBriefList() (map[string]iconPair, error) {
 	url := "https://bybit.com/x-api/contract/v5/product/brief-symbol-list"
-	resp, err := httpClient.Get(url)
+	body, err := getBodyWithRetry(url, true)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("brief-symbol-list error: %s", string(body))
+		return nil, fmt.Errorf("brief-symbol-list error: %w", err)
 	}
 
 	// parse very defensively: result.list [] or list [] or top-level []
@@ -301,18 +291,13 @@ func extractBaseCoin(m map[string]interface{}) string {
 
 func fetchCoinIcon(coin string) (string, error) {
 	// Try Bybit asset coin query-info (public)
-	endpoint := "https://api.bybit.com/v5/asset/coin/query-info"
+	endpoint := defaultEndpoints.rest + "/v5/asset/coin/query-info"
 	q := url.Values{}
 	// Some endpoints expect lowercase coin code; try both later
 	q.Set("coin", strings.ToUpper(coin))
-	resp, err := httpClient.Get(endpoint + "?" + q.Encode())
+	body, err := getBodyWithRetry(endpoint+"?"+q.Encode(), true)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("coin query-info error: %s", string(body))
+		return "", fmt.Errorf("coin query-info error: %w", err)
 	}
 	var raw map[string]interface{}
 	if err := json.Unmarshal(body, &raw); err != nil {
@@ -575,25 +560,41 @@ func getIconCacheDir() string {
 }
 
 func downloadFile(urlStr, dest string) error {
-	resp, err := httpClient.Get(urlStr)
+	// Icons are small; buffering lets a failed attempt be retried cleanly
+	body, err := getBodyWithRetry(urlStr, false)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("download failed: %d %s", resp.StatusCode, string(body))
+		return fmt.Errorf("download failed: %w", err)
 	}
 	tmp := dest + ".part"
-	f, err := os.Create(tmp)
-	if err != nil {
-		return err
-	}
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		f.Close()
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
 		_ = os.Remove(tmp)
 		return err
 	}
-	f.Close()
 	return os.Rename(tmp, dest)
 }
+
+// getBodyWithRetry GETs urlStr with retries and returns the response body.
+// bybitHost routes the request through the shared public rate limiter.
+func getBodyWithRetry(urlStr string, bybitHost bool) ([]byte, error) {
+	ctx := context.Background()
+	var body []byte
+	err := withRetry(ctx, func() error {
+		var resp *http.Response
+		var err error
+		if bybitHost {
+			resp, err = publicGet(ctx, urlStr)
+		} else {
+			resp, err = httpClient.Get(urlStr)
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return newHTTPStatusError(resp)
+		}
+		body, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return body, err
+}
@@ -0,0 +1,243 @@
+package bybit
+
+import (
+	"coin-control/backend/exchange"
+	"context"
+	"strings"
+	"sync"
+)
+
+// ExchangeName is the registry key of the Bybit connector
+const ExchangeName = "bybit"
+
+// Connector adapts BybitService to the exchange.Connector interface. It is kept
+// separate from BybitService so the context-aware methods are not bound to Wails.
+// Public market data comes from the environment of the selected user.
+type Connector struct {
+	service *BybitService
+
+	mu       sync.Mutex
+	userID   string                    // selected with SelectMarkets
+	managers map[any]*WebSocketManager // subscribed channel -> stream serving it
+}
+
+var (
+	_ exchange.Connector    = (*Connector)(nil)
+	_ exchange.IconProvider = (*Connector)(nil)
+
+	_ exchange.OrderBookStreamer = (*Connector)(nil)
+	_ exchange.TradeStreamer     = (*Connector)(nil)
+	_ exchange.KlineProvider     = (*Connector)(nil)
+
+	_ exchange.PriceStatsProvider = (*Connector)(nil)
+	_ exchange.TradeStatsProvider = (*Connector)(nil)
+	_ exchange.MarketSelector     = (*Connector)(nil)
+)
+
+// NewConnector creates a connector backed by the given service
+func NewConnector(service *BybitService) *Connector {
+	return &Connector{service: service, managers: make(map[any]*WebSocketManager)}
+}
+
+// SelectMarkets serves public market data from the environment stored with
+// userID's credentials
+func (c *Connector) SelectMarkets(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userID = userID
+}
+
+// markets returns the endpoints of the selected user, or the default ones
+// before a user is selected or while they have no credentials
+func (c *Connector) markets() endpoints {
+	c.mu.Lock()
+	userID := c.userID
+	c.mu.Unlock()
+	if userID == "" {
+		return defaultEndpoints
+	}
+	ep, err := c.service.userEndpoints(userID)
+	if err != nil {
+		dbg("market data of user %s uses the default environment: %v", userID, err)
+		return defaultEndpoints
+	}
+	return ep
+}
+
+// stream returns the public stream of the selected environment
+func (c *Connector) stream() *WebSocketManager {
+	return webSocketManagerFor(c.markets())
+}
+
+// track remembers which stream serves ch, so it is unsubscribed there even
+// after another environment was selected
+func (c *Connector) track(ch any, ws *WebSocketManager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.managers[ch] = ws
+}
+
+// release forgets ch and returns the stream serving it
+func (c *Connector) release(ch any) *WebSocketManager {
+	c.mu.Lock()
+	ws, ok := c.managers[ch]
+	delete(c.managers, ch)
+	c.mu.Unlock()
+	if !ok {
+		return c.stream()
+	}
+	return ws
+}
+
+// Name returns the registry key of the exchange
+func (c *Connector) Name() string {
+	return ExchangeName
+}
+
+// GetHoldings returns spot holdings of the user
+func (c *Connector) GetHoldings(ctx context.Context, userID string) ([]exchange.Holding, error) {
+	return c.service.getSpotHoldings(ctx, userID)
+}
+
+// GetBalance returns the balance of a single coin for the user
+func (c *Connector) GetBalance(ctx context.Context, userID string, coin string) (*exchange.CoinBalance, error) {
+	return c.service.getAssetBalance(ctx, userID, coin)
+}
+
+// GetTicker returns the latest spot ticker for a coin
+func (c *Connector) GetTicker(ctx context.Context, symbol string) (*exchange.Ticker, error) {
+	return getTicker(ctx, c.markets().rest, symbol)
+}
+
+// ResolvePair maps a coin ("eth"), symbol ("ETHBTC") or pair ("eth/btc") to a spot market
+func (c *Connector) ResolvePair(ctx context.Context, symbol string) (*exchange.Pair, error) {
+	instruments := instrumentsFor(c.markets().rest)
+	var inst *Instrument
+	var err error
+	if base, quote, ok := splitPair(symbol); ok {
+		inst, err = instruments.pair(ctx, base, quote)
+	} else {
+		inst, err = instruments.resolve(ctx, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &exchange.Pair{Base: inst.BaseCoin, Quote: inst.QuoteCoin, Symbol: inst.Symbol}, nil
+}
+
+// SubscribePair starts streaming prices for a market over the public WebSocket
+func (c *Connector) SubscribePair(pair exchange.Pair, opts exchange.SubscribeOptions) (chan exchange.PriceData, error) {
+	ws := c.stream()
+	ch, err := ws.SubscribeSymbol(pair.Symbol, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.track(ch, ws)
+	return ch, nil
+}
+
+// UnsubscribePair stops streaming prices to the given channel
+func (c *Connector) UnsubscribePair(pair exchange.Pair, ch chan exchange.PriceData) {
+	c.release(ch).UnsubscribeSymbol(pair.Symbol, ch)
+}
+
+// PriceStreamStats returns delivery counters of the price subscriptions of
+// every environment in use
+func (c *Connector) PriceStreamStats() []exchange.SubscriberStats {
+	var stats []exchange.SubscriberStats
+	for _, ws := range webSocketManagers() {
+		stats = append(stats, ws.PriceStats()...)
+	}
+	return stats
+}
+
+// GetOrderBook returns the live book when the market is streamed, otherwise a REST snapshot
+func (c *Connector) GetOrderBook(ctx context.Context, pair exchange.Pair, depth int) (*exchange.OrderBook, error) {
+	ep := c.markets()
+	if book, ok := webSocketManagerFor(ep).OrderBookSnapshot(pair.Symbol, depth); ok {
+		return book, nil
+	}
+	return getOrderBook(ctx, ep.rest, pair.Symbol, depth)
+}
+
+// SubscribeOrderBook starts streaming the L2 book of a market at the given depth
+func (c *Connector) SubscribeOrderBook(pair exchange.Pair, depth int) (chan exchange.OrderBook, error) {
+	ws := c.stream()
+	ch, err := ws.SubscribeOrderBook(pair.Symbol, depth)
+	if err != nil {
+		return nil, err
+	}
+	c.track(ch, ws)
+	return ch, nil
+}
+
+// UnsubscribeOrderBook stops streaming the book to the given channel
+func (c *Connector) UnsubscribeOrderBook(pair exchange.Pair, depth int, ch chan exchange.OrderBook) {
+	c.release(ch).UnsubscribeOrderBook(pair.Symbol, depth, ch)
+}
+
+// SubscribeTrades starts streaming public trades of a market
+func (c *Connector) SubscribeTrades(pair exchange.Pair) (chan exchange.Trade, error) {
+	ws := c.stream()
+	ch, err := ws.SubscribeTrades(pair.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	c.track(ch, ws)
+	return ch, nil
+}
+
+// UnsubscribeTrades stops streaming trades to the given channel
+func (c *Connector) UnsubscribeTrades(pair exchange.Pair, ch chan exchange.Trade) {
+	c.release(ch).UnsubscribeTrades(pair.Symbol, ch)
+}
+
+// TradeStreamStats returns delivery and drop counters of the trade
+// subscriptions of every environment in use
+func (c *Connector) TradeStreamStats() []exchange.SubscriberStats {
+	var stats []exchange.SubscriberStats
+	for _, ws := range webSocketManagers() {
+		stats = append(stats, ws.TradeStats()...)
+	}
+	return stats
+}
+
+// GetKlines returns candles of a market in [start, end] (ms), oldest first
+func (c *Connector) GetKlines(ctx context.Context, pair exchange.Pair, interval string, start, end int64) ([]exchange.Candle, error) {
+	return getKlines(ctx, c.markets().rest, pair.Symbol, interval, start, end)
+}
+
+// SubscribeKlines starts streaming candles of a market
+func (c *Connector) SubscribeKlines(pair exchange.Pair, interval string) (chan exchange.Candle, error) {
+	ws := c.stream()
+	ch, err := ws.SubscribeKlines(pair.Symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	c.track(ch, ws)
+	return ch, nil
+}
+
+// UnsubscribeKlines stops streaming candles to the given channel
+func (c *Connector) UnsubscribeKlines(pair exchange.Pair, interval string, ch chan exchange.Candle) {
+	c.release(ch).UnsubscribeKlines(pair.Symbol, interval, ch)
+}
+
+// GetCoinIconURLs returns icon URLs for the given coins
+func (c *Connector) GetCoinIconURLs(coins []string) ([]exchange.IconEntry, error) {
+	return c.service.GetCoinIconURLs(coins)
+}
+
+// PrefetchCoinIcons warms the icon disk cache in the background
+func (c *Connector) PrefetchCoinIcons(coins []string) {
+	c.service.PrefetchCoinIcons(coins)
+}
+
+// splitPair splits "eth/btc" or "eth-btc" into its base and quote coin
+func splitPair(symbol string) (string, string, bool) {
+	parts := strings.FieldsFunc(symbol, func(r rune) bool { return r == '/' || r == '-' })
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,24 @@
+package bybit
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// parseDecimal parses an exchange decimal string exactly
+func parseDecimal(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+// isMultipleOf reports whether v is an integer multiple of step. A zero step matches anything.
+func isMultipleOf(v, step *big.Rat) bool {
+	if step.Sign() == 0 {
+		return true
+	}
+	return new(big.Rat).Quo(v, step).IsInt()
+}
@@ -0,0 +1,128 @@
+package bybit
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Environment selects which Bybit deployment REST and WebSocket calls go to
+type Environment string
+
+const (
+	EnvMainnet Environment = "mainnet"
+	EnvTestnet Environment = "testnet"
+	EnvDemo    Environment = "demo"
+	EnvCustom  Environment = "custom"
+)
+
+// endpoints holds the hosts used for a single environment
+type endpoints struct {
+	rest      string // REST base, e.g. https://api.bybit.com
+	publicWS  string // public stream base, e.g. wss://stream.bybit.com
+	privateWS string // private stream base
+}
+
+// defaultEndpoints serves public market data until a user is selected, and
+// for users without credentials. It is configured from BYBIT_ENV and
+// BYBIT_BASE_URL and falls back to mainnet.
+var defaultEndpoints = endpoints{
+	rest:      "https://api.bybit.com",
+	publicWS:  "wss://stream.bybit.com",
+	privateWS: "wss://stream.bybit.com",
+}
+
+func init() {
+	env := Environment(strings.ToLower(os.Getenv("BYBIT_ENV")))
+	if env == "" {
+		env = EnvMainnet
+	}
+	ep, err := resolveEndpoints(env, os.Getenv("BYBIT_BASE_URL"))
+	if err != nil {
+		log.Printf("Ignoring BYBIT_ENV/BYBIT_BASE_URL: %v", err)
+		return
+	}
+	defaultEndpoints = ep
+}
+
+// parseEnvironment normalizes an environment name, treating empty as mainnet
+func parseEnvironment(value string) (Environment, error) {
+	env := Environment(strings.ToLower(strings.TrimSpace(value)))
+	switch env {
+	case "":
+		return EnvMainnet, nil
+	case EnvMainnet, EnvTestnet, EnvDemo, EnvCustom:
+		return env, nil
+	default:
+		return "", fmt.Errorf("unknown bybit environment %q", value)
+	}
+}
+
+// resolveEndpoints returns the REST and WebSocket hosts for an environment.
+// baseURL is only used (and required) for EnvCustom.
+func resolveEndpoints(env Environment, baseURL string) (endpoints, error) {
+	switch env {
+	case EnvMainnet, "":
+		return endpoints{
+			rest:      "https://api.bybit.com",
+			publicWS:  "wss://stream.bybit.com",
+			privateWS: "wss://stream.bybit.com",
+		}, nil
+	case EnvTestnet:
+		return endpoints{
+			rest:      "https://api-testnet.bybit.com",
+			publicWS:  "wss://stream-testnet.bybit.com",
+			privateWS: "wss://stream-testnet.bybit.com",
+		}, nil
+	case EnvDemo:
+		// Demo trading has its own REST and private stream hosts but serves
+		// market data from mainnet
+		return endpoints{
+			rest:      "https://api-demo.bybit.com",
+			publicWS:  "wss://stream.bybit.com",
+			privateWS: "wss://stream-demo.bybit.com",
+		}, nil
+	case EnvCustom:
+		rest, err := normalizeBaseURL(baseURL)
+		if err != nil {
+			return endpoints{}, err
+		}
+		ws := "ws" + strings.TrimPrefix(rest, "http")
+		return endpoints{rest: rest, publicWS: ws, privateWS: ws}, nil
+	default:
+		return endpoints{}, fmt.Errorf("unknown bybit environment %q", env)
+	}
+}
+
+// normalizeBaseURL validates a custom http(s) base URL and strips the trailing slash
+func normalizeBaseURL(baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid bybit base url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("bybit base url must be an absolute http(s) url, got %q", baseURL)
+	}
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/"), nil
+}
+
+// endpoints returns the hosts configured for this credential
+func (b *Bybit) endpoints() (endpoints, error) {
+	env, err := parseEnvironment(b.Environment)
+	if err != nil {
+		return endpoints{}, err
+	}
+	return resolveEndpoints(env, b.BaseURL)
+}
+
+// userEndpoints returns the hosts of the environment stored with the user's
+// credentials, so public market data matches the markets they trade on
+func (s *BybitService) userEndpoints(userID string) (endpoints, error) {
+	creds, err := s.GetBybitByUserId(userID)
+	if err != nil {
+		return endpoints{}, fmt.Errorf("bybit credentials not found: %w", err)
+	}
+	return creds.endpoints()
+}
@@ -0,0 +1,115 @@
+package bybit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Bybit v5 retCodes that callers need to tell apart
+const (
+	retCodeTimestampExpired = 10002
+	retCodeInvalidAPIKey    = 10003
+	retCodeInvalidSign      = 10004
+	retCodePermissionDenied = 10005
+	retCodeTooManyVisits    = 10006
+	retCodeAuthFailed       = 10007
+	retCodeIPNotAllowed     = 10010
+	retCodeIPRateLimit      = 10018
+	retCodeAPIKeyExpired    = 33004
+	retCodeSystemRateLimit  = 10429
+)
+
+// Stable error codes surfaced to the frontend
+const (
+	ErrCodeAuth        = "bybit_auth"
+	ErrCodeIPRejected  = "bybit_ip_rejected"
+	ErrCodeRateLimited = "bybit_rate_limited"
+	ErrCodeTimestamp   = "bybit_timestamp"
+	ErrCodeAPI         = "bybit_api"
+)
+
+// APIError is returned when Bybit rejects a request, either with a non-zero
+// retCode in the response envelope or with a non-2xx HTTP status
+type APIError struct {
+	RetCode    int    `json:"retCode"`
+	RetMsg     string `json:"retMsg"`
+	HTTPStatus int    `json:"httpStatus"`
+	Endpoint   string `json:"endpoint"`
+}
+
+func (e *APIError) Error() string {
+	if e.RetCode == 0 {
+		return fmt.Sprintf("bybit %s error: status %d: %s", e.Endpoint, e.HTTPStatus, e.RetMsg)
+	}
+	return fmt.Sprintf("bybit %s error retCode=%d: %s", e.Endpoint, e.RetCode, e.RetMsg)
+}
+
+// IsAuthError reports whether the API key, secret or its permissions were rejected
+func (e *APIError) IsAuthError() bool {
+	switch e.RetCode {
+	case retCodeInvalidAPIKey, retCodeInvalidSign, retCodePermissionDenied, retCodeAuthFailed, retCodeAPIKeyExpired:
+		return true
+	}
+	return e.HTTPStatus == http.StatusUnauthorized
+}
+
+// IsIPRejected reports whether the request came from an IP outside the key's whitelist
+func (e *APIError) IsIPRejected() bool {
+	return e.RetCode == retCodeIPNotAllowed
+}
+
+// IsRateLimited reports whether Bybit throttled the request
+func (e *APIError) IsRateLimited() bool {
+	switch e.RetCode {
+	case retCodeTooManyVisits, retCodeIPRateLimit, retCodeSystemRateLimit:
+		return true
+	}
+	// Bybit answers IP-level throttling with a bare 403
+	return e.HTTPStatus == http.StatusForbidden || e.HTTPStatus == http.StatusTooManyRequests
+}
+
+// IsTimestampError reports whether the request timestamp was outside recvWindow
+func (e *APIError) IsTimestampError() bool {
+	return e.RetCode == retCodeTimestampExpired
+}
+
+// Code returns a stable, frontend-facing classification of the error
+func (e *APIError) Code() string {
+	switch {
+	case e.IsIPRejected():
+		return ErrCodeIPRejected
+	case e.IsAuthError():
+		return ErrCodeAuth
+	case e.IsRateLimited():
+		return ErrCodeRateLimited
+	case e.IsTimestampError():
+		return ErrCodeTimestamp
+	default:
+		return ErrCodeAPI
+	}
+}
+
+// ErrorPayload is the structured form of an APIError sent to the frontend
+type ErrorPayload struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RetCode    int    `json:"retCode"`
+	HTTPStatus int    `json:"httpStatus"`
+	Endpoint   string `json:"endpoint"`
+}
+
+// AsErrorPayload converts err into an ErrorPayload if it wraps an *APIError
+func AsErrorPayload(err error) (*ErrorPayload, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return nil, false
+	}
+	return &ErrorPayload{
+		Code:       apiErr.Code(),
+		Message:    err.Error(),
+		RetCode:    apiErr.RetCode,
+		HTTPStatus: apiErr.HTTPStatus,
+		Endpoint:   apiErr.Endpoint,
+	}, true
+}
@@ -0,0 +1,151 @@
+package bybit
+
+import (
+	"coin-control/backend/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// TaskSyncExecutions fetches a user's fills into the local ledger
+	TaskSyncExecutions = "bybit:sync_executions"
+
+	// executionHistory is how far back Bybit serves executions
+	executionHistory = 2 * 365 * 24 * time.Hour
+
+	// executionSyncInline is the longest sync a P&L query waits for; longer
+	// ones, such as a user's first sync, run on the task queue
+	executionSyncInline = 4 * executionWindow
+
+	// executionSyncEvent reports the progress of a queued sync
+	executionSyncEvent = "executions-sync"
+)
+
+// syncExecutionsPayload is the payload of TaskSyncExecutions
+type syncExecutionsPayload struct {
+	UserID string `json:"userId"`
+}
+
+// ExecutionSyncProgress is emitted after every window of a queued sync
+type ExecutionSyncProgress struct {
+	UserID   string `json:"userId"`
+	Done     int    `json:"done"`     // windows fetched
+	Total    int    `json:"total"`    // windows to fetch
+	Finished bool   `json:"finished"` // the attempt ended; Error is set if it failed
+	Error    string `json:"error,omitempty"`
+}
+
+// executionSyncStart returns where the user's next sync starts: shortly
+// before the last one ended, or as far back as Bybit allows
+func executionSyncStart(ctx context.Context, userID string, now time.Time) (time.Time, error) {
+	start := now.Add(-executionHistory)
+	syncedUntil, ok, err := database.GetExecutionSyncTime(ctx, userID, ExchangeName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ok && syncedUntil.After(start) {
+		// Overlap a little; stored fills are not duplicated
+		start = syncedUntil.Add(-time.Minute)
+	}
+	return start, nil
+}
+
+// syncExecutions fetches the user's fills since the last sync one window at a
+// time. The sync time is recorded after each window, so an interrupted sync
+// resumes where it stopped. progress, if set, is called after each window.
+func (s *BybitService) syncExecutions(ctx context.Context, userID string, progress func(done, total int)) error {
+	now := time.Now()
+	start, err := executionSyncStart(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	total := int((now.Sub(start) + executionWindow - 1) / executionWindow)
+	done := 0
+	for from := start; from.Before(now); from = from.Add(executionWindow) {
+		to := from.Add(executionWindow - time.Millisecond)
+		if to.After(now) {
+			to = now
+		}
+		if _, err := s.getExecutions(ctx, userID, "", from.UnixMilli(), to.UnixMilli()); err != nil {
+			return err
+		}
+		if err := database.SetExecutionSyncTime(ctx, userID, ExchangeName, to); err != nil {
+			return err
+		}
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+	return nil
+}
+
+// syncOrQueueExecutions syncs the user's fills inline when few windows are
+// missing and queues TaskSyncExecutions otherwise. It reports whether a sync
+// was queued and the stored fills are still incomplete.
+func (s *BybitService) syncOrQueueExecutions(ctx context.Context, userID string) (bool, error) {
+	start, err := executionSyncStart(ctx, userID, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if time.Since(start) <= executionSyncInline || taskQueue == nil {
+		return false, s.syncExecutions(ctx, userID, nil)
+	}
+	if err := enqueueExecutionSync(userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// enqueueExecutionSync queues a sync of the user's fills. A sync already
+// pending for the user is not queued twice.
+func enqueueExecutionSync(userID string) error {
+	payload, err := json.Marshal(syncExecutionsPayload{UserID: userID})
+	if err != nil {
+		return err
+	}
+	task := asynq.NewTask(TaskSyncExecutions, payload)
+	err = taskQueue.Enqueue(task, asynq.MaxRetry(3), asynq.Unique(time.Hour))
+	if err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		return err
+	}
+	return nil
+}
+
+// handleSyncExecutions runs a queued sync and emits its progress
+func (s *BybitService) handleSyncExecutions(ctx context.Context, t *asynq.Task) error {
+	var p syncExecutionsPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil || p.UserID == "" {
+		return fmt.Errorf("bad %s payload: %v: %w", TaskSyncExecutions, err, asynq.SkipRetry)
+	}
+
+	status := ExecutionSyncProgress{UserID: p.UserID}
+	err := s.syncExecutions(ctx, p.UserID, func(done, total int) {
+		status.Done, status.Total = done, total
+		emitExecutionSync(status)
+	})
+	status.Finished = true
+	if err != nil {
+		status.Error = err.Error()
+	}
+	emitExecutionSync(status)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Synced %d execution window(s) of user %s", status.Done, p.UserID)
+	return nil
+}
+
+func emitExecutionSync(status ExecutionSyncProgress) {
+	if ctx := getRuntimeCtx(); ctx != nil {
+		runtime.EventsEmit(ctx, executionSyncEvent, status)
+	}
+}
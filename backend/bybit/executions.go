@@ -0,0 +1,180 @@
+package bybit
+
+import (
+	"coin-control/backend/database"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// executionWindow is the widest startTime..endTime span Bybit accepts
+	executionWindow = 7 * 24 * time.Hour
+	// executionPageSize is the largest page /v5/execution/list returns
+	executionPageSize = "100"
+)
+
+// executionRecord mirrors an entry of /v5/execution/list
+type executionRecord struct {
+	ExecID      string `json:"execId"`
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	ExecPrice   string `json:"execPrice"`
+	ExecQty     string `json:"execQty"`
+	ExecFee     string `json:"execFee"`
+	FeeCurrency string `json:"feeCurrency"`
+	IsMaker     bool   `json:"isMaker"`
+	ExecTime    string `json:"execTime"`
+}
+
+func (r executionRecord) toExecution(instruments *instrumentCache) Execution {
+	feeCurrency := r.FeeCurrency
+	if feeCurrency == "" {
+		feeCurrency = spotFeeCurrency(instruments, r.Symbol, r.Side)
+	}
+	return Execution{
+		ExecID:      r.ExecID,
+		OrderID:     r.OrderID,
+		OrderLinkID: r.OrderLinkID,
+		Symbol:      r.Symbol,
+		Side:        r.Side,
+		Price:       r.ExecPrice,
+		Qty:         r.ExecQty,
+		Fee:         toNumString(r.ExecFee),
+		FeeCurrency: feeCurrency,
+		IsMaker:     r.IsMaker,
+		ExecTime:    parseMillis(r.ExecTime),
+	}
+}
+
+// spotFeeCurrency infers the fee coin for records that omit it: Bybit spot
+// charges buys in the base coin and sells in the quote coin
+func spotFeeCurrency(instruments *instrumentCache, symbol, side string) string {
+	inst, ok := instruments.peek(symbol)
+	if !ok {
+		return ""
+	}
+	if strings.EqualFold(side, SideBuy) {
+		return inst.BaseCoin
+	}
+	return inst.QuoteCoin
+}
+
+// getExecutions fetches spot fills in [startTime, endTime] (ms) from Bybit,
+// walking the range in 7-day windows, and stores them in the local ledger
+func (s *BybitService) getExecutions(ctx context.Context, userID, symbol string, startTime, endTime int64) ([]Execution, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instruments are only needed to infer missing fee currencies
+	instruments := instrumentsFor(client.baseURL)
+	if err := instruments.ensureLoaded(ctx); err != nil {
+		dbg("instruments unavailable for fee currency inference: %v", err)
+	}
+
+	end := time.Now()
+	if endTime > 0 {
+		end = time.UnixMilli(endTime)
+	}
+	start := end.Add(-executionWindow)
+	if startTime > 0 {
+		start = time.UnixMilli(startTime)
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("startTime must not be after endTime")
+	}
+
+	var execs []Execution
+	for windowStart := start; !windowStart.After(end); windowStart = windowStart.Add(executionWindow) {
+		windowEnd := windowStart.Add(executionWindow - time.Millisecond)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		q := url.Values{}
+		q.Set("category", "spot")
+		q.Set("limit", executionPageSize)
+		q.Set("startTime", strconv.FormatInt(windowStart.UnixMilli(), 10))
+		q.Set("endTime", strconv.FormatInt(windowEnd.UnixMilli(), 10))
+		if symbol != "" {
+			q.Set("symbol", strings.ToUpper(symbol))
+		}
+
+		records, err := getAllPages[executionRecord](ctx, client, "/v5/execution/list", q, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			execs = append(execs, r.toExecution(instruments))
+		}
+	}
+
+	if err := storeExecutions(ctx, userID, execs); err != nil {
+		return nil, err
+	}
+	return execs, nil
+}
+
+// storeExecutions persists fills to the local ledger
+func storeExecutions(ctx context.Context, userID string, execs []Execution) error {
+	rows := make([]database.Execution, 0, len(execs))
+	for _, e := range execs {
+		rows = append(rows, database.Execution{
+			ExecID:      e.ExecID,
+			UserID:      userID,
+			Exchange:    ExchangeName,
+			Symbol:      e.Symbol,
+			OrderID:     e.OrderID,
+			OrderLinkID: e.OrderLinkID,
+			Side:        e.Side,
+			Price:       e.Price,
+			Qty:         e.Qty,
+			Fee:         e.Fee,
+			FeeCurrency: e.FeeCurrency,
+			IsMaker:     e.IsMaker,
+			ExecTime:    time.UnixMilli(e.ExecTime),
+		})
+	}
+	return database.SaveExecutions(ctx, rows)
+}
+
+// getStoredExecutions reads fills from the local ledger without calling Bybit
+func getStoredExecutions(ctx context.Context, userID, symbol string, startTime, endTime int64) ([]Execution, error) {
+	from := time.Unix(0, 0)
+	if startTime > 0 {
+		from = time.UnixMilli(startTime)
+	}
+	to := time.Now()
+	if endTime > 0 {
+		to = time.UnixMilli(endTime)
+	}
+
+	rows, err := database.GetExecutions(ctx, userID, ExchangeName, strings.ToUpper(symbol), from, to)
+	if err != nil {
+		return nil, err
+	}
+	execs := make([]Execution, 0, len(rows))
+	for _, r := range rows {
+		execs = append(execs, Execution{
+			ExecID:      r.ExecID,
+			OrderID:     r.OrderID,
+			OrderLinkID: r.OrderLinkID,
+			Symbol:      r.Symbol,
+			Side:        r.Side,
+			Price:       r.Price,
+			Qty:         r.Qty,
+			Fee:         r.Fee,
+			FeeCurrency: r.FeeCurrency,
+			IsMaker:     r.IsMaker,
+			ExecTime:    r.ExecTime.UnixMilli(),
+		})
+	}
+	return execs, nil
+}
@@ -0,0 +1,279 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// instrumentsRefreshInterval is how often the spot instrument list is reloaded
+	instrumentsRefreshInterval = time.Hour
+	// instrumentsMissRefresh is the minimum age before an unknown symbol forces a reload
+	instrumentsMissRefresh = time.Minute
+)
+
+// preferredQuotes is the order in which quote coins are tried when only a base coin is given
+var preferredQuotes = []string{"USDT", "USDC", "BTC", "ETH", "EUR"}
+
+// Instrument holds the trading rules of a spot symbol
+type Instrument struct {
+	Symbol      string `json:"symbol"`
+	BaseCoin    string `json:"baseCoin"`
+	QuoteCoin   string `json:"quoteCoin"`
+	Status      string `json:"status"`
+	TickSize    string `json:"tickSize"`
+	LotSize     string `json:"lotSize"`     // base coin quantity step
+	QuoteStep   string `json:"quoteStep"`   // quote coin amount step for market orders
+	MinOrderQty string `json:"minOrderQty"` // in base coin
+	MaxOrderQty string `json:"maxOrderQty"` // in base coin
+	MinNotional string `json:"minNotional"` // minimum order value in quote coin
+}
+
+// IsTrading reports whether orders and streams are available for the instrument
+func (i *Instrument) IsTrading() bool {
+	return strings.EqualFold(i.Status, "Trading")
+}
+
+// instrumentRecord mirrors an entry of /v5/market/instruments-info for spot
+type instrumentRecord struct {
+	Symbol        string `json:"symbol"`
+	BaseCoin      string `json:"baseCoin"`
+	QuoteCoin     string `json:"quoteCoin"`
+	Status        string `json:"status"`
+	LotSizeFilter struct {
+		BasePrecision  string `json:"basePrecision"`
+		QuotePrecision string `json:"quotePrecision"`
+		MinOrderQty    string `json:"minOrderQty"`
+		MaxOrderQty    string `json:"maxOrderQty"`
+		MinOrderAmt    string `json:"minOrderAmt"`
+	} `json:"lotSizeFilter"`
+	PriceFilter struct {
+		TickSize string `json:"tickSize"`
+	} `json:"priceFilter"`
+}
+
+func (r instrumentRecord) toInstrument() Instrument {
+	return Instrument{
+		Symbol:      r.Symbol,
+		BaseCoin:    r.BaseCoin,
+		QuoteCoin:   r.QuoteCoin,
+		Status:      r.Status,
+		TickSize:    r.PriceFilter.TickSize,
+		LotSize:     r.LotSizeFilter.BasePrecision,
+		QuoteStep:   r.LotSizeFilter.QuotePrecision,
+		MinOrderQty: r.LotSizeFilter.MinOrderQty,
+		MaxOrderQty: r.LotSizeFilter.MaxOrderQty,
+		MinNotional: r.LotSizeFilter.MinOrderAmt,
+	}
+}
+
+// instrumentCache holds the spot instruments of one Bybit host
+type instrumentCache struct {
+	baseURL  string
+	mu       sync.RWMutex
+	loadMu   sync.Mutex // serializes reloads
+	bySymbol map[string]Instrument
+	byBase   map[string][]Instrument
+	loadedAt time.Time
+}
+
+var (
+	instrumentCachesMu sync.Mutex
+	instrumentCaches   = map[string]*instrumentCache{}
+)
+
+// instrumentsFor returns the cache for baseURL, starting its refresh schedule on first use
+func instrumentsFor(baseURL string) *instrumentCache {
+	instrumentCachesMu.Lock()
+	defer instrumentCachesMu.Unlock()
+	c, ok := instrumentCaches[baseURL]
+	if !ok {
+		c = &instrumentCache{baseURL: baseURL}
+		instrumentCaches[baseURL] = c
+		go c.refreshLoop()
+	}
+	return c
+}
+
+func (c *instrumentCache) refreshLoop() {
+	ticker := time.NewTicker(instrumentsRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := c.refresh(context.Background()); err != nil {
+			dbg("instrument refresh for %s failed: %v", c.baseURL, err)
+		}
+	}
+}
+
+// refresh reloads every spot instrument from /v5/market/instruments-info
+func (c *instrumentCache) refresh(ctx context.Context) error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	q := url.Values{}
+	q.Set("category", "spot")
+	q.Set("limit", "1000")
+
+	var records []instrumentRecord
+	for page := 0; page < maxPages; page++ {
+		reqURL := c.baseURL + "/v5/market/instruments-info?" + q.Encode()
+		var result cursorPage[instrumentRecord]
+		err := withRetry(ctx, func() error {
+			resp, err := publicGet(ctx, reqURL)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decodeEnvelope(resp, "/v5/market/instruments-info", &result)
+		})
+		if err != nil {
+			return err
+		}
+		records = append(records, result.List...)
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			break
+		}
+		q.Set("cursor", result.NextPageCursor)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no spot instruments returned by %s", c.baseURL)
+	}
+
+	bySymbol := make(map[string]Instrument, len(records))
+	byBase := make(map[string][]Instrument)
+	for _, r := range records {
+		inst := r.toInstrument()
+		bySymbol[inst.Symbol] = inst
+		byBase[inst.BaseCoin] = append(byBase[inst.BaseCoin], inst)
+	}
+
+	c.mu.Lock()
+	c.bySymbol = bySymbol
+	c.byBase = byBase
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+	dbg("loaded %d spot instruments from %s", len(records), c.baseURL)
+	return nil
+}
+
+// ensureLoaded loads the cache if it has never been loaded
+func (c *instrumentCache) ensureLoaded(ctx context.Context) error {
+	c.mu.RLock()
+	loaded := c.bySymbol != nil
+	c.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+	return c.refresh(ctx)
+}
+
+// peek returns a cached instrument without loading or refreshing
+func (c *instrumentCache) peek(symbol string) (Instrument, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	inst, ok := c.bySymbol[strings.ToUpper(symbol)]
+	return inst, ok
+}
+
+// lookup returns the instrument for an exact symbol such as "ETHBTC". An unknown
+// symbol triggers one reload in case it was listed after the last refresh.
+func (c *instrumentCache) lookup(ctx context.Context, symbol string) (*Instrument, error) {
+	if err := c.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if inst, ok := c.peek(symbol); ok {
+		return &inst, nil
+	}
+
+	c.mu.RLock()
+	stale := time.Since(c.loadedAt) > instrumentsMissRefresh
+	c.mu.RUnlock()
+	if stale {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if inst, ok := c.peek(symbol); ok {
+			return &inst, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown spot symbol %s", symbol)
+}
+
+// resolve maps either a full symbol ("ETHBTC") or a bare coin ("eth") to a
+// trading instrument. A coin is paired with the first available preferred quote.
+func (c *instrumentCache) resolve(ctx context.Context, coinOrSymbol string) (*Instrument, error) {
+	if err := c.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	key := strings.ToUpper(strings.TrimSpace(coinOrSymbol))
+	if key == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	if inst, ok := c.peek(key); ok {
+		return &inst, nil
+	}
+
+	c.mu.RLock()
+	candidates := c.byBase[key]
+	c.mu.RUnlock()
+	for _, quote := range preferredQuotes {
+		for i := range candidates {
+			if candidates[i].QuoteCoin == quote && candidates[i].IsTrading() {
+				inst := candidates[i]
+				return &inst, nil
+			}
+		}
+	}
+	for i := range candidates {
+		if candidates[i].IsTrading() {
+			inst := candidates[i]
+			return &inst, nil
+		}
+	}
+	return nil, fmt.Errorf("no spot market found for %s", key)
+}
+
+// pair returns the instrument for base/quote, e.g. ("eth", "btc") -> ETHBTC
+func (c *instrumentCache) pair(ctx context.Context, base, quote string) (*Instrument, error) {
+	if err := c.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, inst := range c.byBase[base] {
+		if inst.QuoteCoin == quote {
+			return &inst, nil
+		}
+	}
+	return nil, fmt.Errorf("no spot market for %s/%s", base, quote)
+}
+
+// list returns every cached instrument
+func (c *instrumentCache) list(ctx context.Context) ([]Instrument, error) {
+	if err := c.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Instrument, 0, len(c.bySymbol))
+	for _, inst := range c.bySymbol {
+		out = append(out, inst)
+	}
+	return out, nil
+}
+
+// resolveSpotSymbol maps a coin or symbol to a Bybit spot symbol for public
+// market data, validating it against the instruments of one environment
+func resolveSpotSymbol(ctx context.Context, instruments *instrumentCache, coinOrSymbol string) (string, error) {
+	inst, err := instruments.resolve(ctx, coinOrSymbol)
+	if err != nil {
+		return "", err
+	}
+	return inst.Symbol, nil
+}
@@ -0,0 +1,224 @@
+package bybit
+
+import (
+	"coin-control/backend/exchange"
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Candle = exchange.Candle
+
+// klinePageSize is the maximum number of candles /v5/market/kline returns per call
+const klinePageSize = 1000
+
+// klineIntervals maps Bybit interval codes to their length; months vary and are
+// handled by intervalEnd
+var klineIntervals = map[string]time.Duration{
+	"1":   time.Minute,
+	"3":   3 * time.Minute,
+	"5":   5 * time.Minute,
+	"15":  15 * time.Minute,
+	"30":  30 * time.Minute,
+	"60":  time.Hour,
+	"120": 2 * time.Hour,
+	"240": 4 * time.Hour,
+	"360": 6 * time.Hour,
+	"720": 12 * time.Hour,
+	"D":   24 * time.Hour,
+	"W":   7 * 24 * time.Hour,
+	"M":   0,
+}
+
+// intervalAliases accepts the common chart notation next to Bybit's codes
+var intervalAliases = map[string]string{
+	"1m": "1", "3m": "3", "5m": "5", "15m": "15", "30m": "30",
+	"1h": "60", "2h": "120", "4h": "240", "6h": "360", "12h": "720",
+	"1d": "D", "1w": "W", "1M": "M",
+}
+
+// normalizeInterval maps an interval such as "15", "1h" or "D" to a Bybit code
+func normalizeInterval(interval string) (string, error) {
+	if code, ok := intervalAliases[interval]; ok {
+		return code, nil
+	}
+	code := strings.ToUpper(interval)
+	if _, ok := klineIntervals[code]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("unsupported kline interval %q", interval)
+}
+
+// intervalEnd returns the last millisecond of the candle starting at start
+func intervalEnd(start int64, interval string) int64 {
+	if interval == "M" {
+		return time.UnixMilli(start).UTC().AddDate(0, 1, 0).UnixMilli() - 1
+	}
+	return start + klineIntervals[interval].Milliseconds() - 1
+}
+
+// defaultKlineStart returns the start of a range of one page of candles ending at end
+func defaultKlineStart(end int64, interval string) int64 {
+	if interval == "M" {
+		return time.UnixMilli(end).UTC().AddDate(0, -klinePageSize, 0).UnixMilli()
+	}
+	return end - int64(klinePageSize)*klineIntervals[interval].Milliseconds()
+}
+
+// klineTopic returns the public kline topic of a symbol and interval
+func klineTopic(symbol, interval string) string {
+	return fmt.Sprintf("kline.%s.%s", interval, symbol)
+}
+
+// klineResult is the /v5/market/kline result. Each row is
+// [startTime, open, high, low, close, volume, turnover], newest first.
+type klineResult struct {
+	Symbol string      `json:"symbol"`
+	List   [][7]string `json:"list"`
+}
+
+// klineRecord is a single entry of a kline.{interval}.{symbol} message
+type klineRecord struct {
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Interval  string `json:"interval"`
+	Open      string `json:"open"`
+	Close     string `json:"close"`
+	High      string `json:"high"`
+	Low       string `json:"low"`
+	Volume    string `json:"volume"`
+	Turnover  string `json:"turnover"`
+	Confirm   bool   `json:"confirm"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (r klineRecord) toCandle(symbol string) Candle {
+	return Candle{
+		Symbol:    symbol,
+		Interval:  r.Interval,
+		Start:     r.Start,
+		End:       r.End,
+		Open:      r.Open,
+		High:      r.High,
+		Low:       r.Low,
+		Close:     r.Close,
+		Volume:    r.Volume,
+		Turnover:  r.Turnover,
+		Confirmed: r.Confirm,
+	}
+}
+
+// getKlines fetches candles of a spot symbol in [start, end] (ms) from the
+// REST host rest, oldest first. A missing start returns the last 1000 candles
+// before end.
+// Bybit returns the newest candles of a range first, so long ranges are walked
+// backwards from end. Candles still in progress at the time of the call are
+// returned with Confirmed unset.
+func getKlines(ctx context.Context, rest, symbol, interval string, start, end int64) ([]Candle, error) {
+	code, err := normalizeInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := instrumentsFor(rest).lookup(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if end <= 0 {
+		end = time.Now().UnixMilli()
+	}
+	if start <= 0 {
+		// Without a start, return one page rather than walking back to the epoch
+		start = defaultKlineStart(end, code)
+	}
+	if start > end {
+		return nil, fmt.Errorf("kline start %d is after end %d", start, end)
+	}
+
+	candles, covered, err := fetchKlines(ctx, rest, inst.Symbol, code, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if covered > start {
+		return nil, fmt.Errorf("bybit /v5/market/kline: pagination did not finish after %d pages", maxPages)
+	}
+	return candles, nil
+}
+
+// fetchKlines pages /v5/market/kline backwards from end towards start. It
+// returns the candles and the oldest time the pages cover, which is start
+// unless maxPages ran out first; open times from there to end that have no
+// candle do not exist on Bybit.
+func fetchKlines(ctx context.Context, rest, symbol, code string, start, end int64) ([]Candle, int64, error) {
+	trackServerClock(rest)
+	now := serverNow(rest)
+	byStart := make(map[int64]Candle)
+	cursor := end
+	for page := 0; page < maxPages; page++ {
+		q := url.Values{}
+		q.Set("category", "spot")
+		q.Set("symbol", symbol)
+		q.Set("interval", code)
+		q.Set("start", strconv.FormatInt(start, 10))
+		q.Set("end", strconv.FormatInt(cursor, 10))
+		q.Set("limit", strconv.Itoa(klinePageSize))
+		reqURL := rest + "/v5/market/kline?" + q.Encode()
+
+		var result klineResult
+		err := withRetry(ctx, func() error {
+			resp, err := publicGet(ctx, reqURL)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decodeEnvelope(resp, "/v5/market/kline", &result)
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		oldest := cursor
+		for _, row := range result.List {
+			ts, err := strconv.ParseInt(row[0], 10, 64)
+			if err != nil {
+				return nil, 0, fmt.Errorf("bad kline start time %q: %w", row[0], err)
+			}
+			candleEnd := intervalEnd(ts, code)
+			byStart[ts] = Candle{
+				Symbol:    symbol,
+				Interval:  code,
+				Start:     ts,
+				End:       candleEnd,
+				Open:      row[1],
+				High:      row[2],
+				Low:       row[3],
+				Close:     row[4],
+				Volume:    row[5],
+				Turnover:  row[6],
+				Confirmed: candleEnd < now,
+			}
+			if ts < oldest {
+				oldest = ts
+			}
+		}
+
+		if len(result.List) < klinePageSize || oldest <= start {
+			return sortCandles(byStart), start, nil
+		}
+		cursor = oldest - 1
+	}
+	return sortCandles(byStart), cursor + 1, nil
+}
+
+// sortCandles returns the candles ordered by start time
+func sortCandles(byStart map[int64]Candle) []Candle {
+	candles := make([]Candle, 0, len(byStart))
+	for _, c := range byStart {
+		candles = append(candles, c)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Start < candles[j].Start })
+	return candles
+}
@@ -0,0 +1,207 @@
+package bybit
+
+import (
+	"coin-control/backend/exchange"
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type OrderBook = exchange.OrderBook
+type OrderBookLevel = exchange.OrderBookLevel
+
+// supportedBookDepths are the depths offered by Bybit's spot orderbook topic
+var supportedBookDepths = map[int]bool{1: true, 50: true, 200: true}
+
+// bookData mirrors the data of an orderbook.{depth}.{symbol} message and of
+// the /v5/market/orderbook result
+type bookData struct {
+	Symbol   string      `json:"s"`
+	Bids     [][2]string `json:"b"`
+	Asks     [][2]string `json:"a"`
+	UpdateID int64       `json:"u"`
+	Seq      int64       `json:"seq"`
+	Ts       int64       `json:"ts"`
+}
+
+// localBook is an L2 book maintained from snapshot and delta messages
+type localBook struct {
+	symbol   string
+	base     string
+	quote    string
+	depth    int
+	bids     map[string]string // price -> size
+	asks     map[string]string
+	updateID int64
+	time     int64
+	synced   bool
+}
+
+func newLocalBook(inst *Instrument, depth int) *localBook {
+	return &localBook{symbol: inst.Symbol, base: inst.BaseCoin, quote: inst.QuoteCoin, depth: depth}
+}
+
+// orderBookTopic returns the public topic name for a symbol and depth
+func orderBookTopic(symbol string, depth int) string {
+	return fmt.Sprintf("orderbook.%d.%s", depth, symbol)
+}
+
+// validateBookDepth rejects depths the spot orderbook topic does not offer
+func validateBookDepth(depth int) error {
+	if !supportedBookDepths[depth] {
+		return fmt.Errorf("unsupported order book depth %d (use 1, 50 or 200)", depth)
+	}
+	return nil
+}
+
+// applySnapshot replaces the whole book
+func (b *localBook) applySnapshot(d bookData, ts int64) {
+	b.bids = make(map[string]string, len(d.Bids))
+	b.asks = make(map[string]string, len(d.Asks))
+	applyLevels(b.bids, d.Bids)
+	applyLevels(b.asks, d.Asks)
+	b.updateID = d.UpdateID
+	b.time = ts
+	b.synced = true
+}
+
+// applyDelta applies an incremental update. It returns an error and marks the
+// book unsynced when an update id was skipped, so the caller can resync.
+func (b *localBook) applyDelta(d bookData, ts int64) error {
+	if !b.synced {
+		return fmt.Errorf("%s: delta received before snapshot", b.symbol)
+	}
+	if d.UpdateID != b.updateID+1 {
+		b.synced = false
+		return fmt.Errorf("%s: update id gap, expected %d got %d", b.symbol, b.updateID+1, d.UpdateID)
+	}
+	applyLevels(b.bids, d.Bids)
+	applyLevels(b.asks, d.Asks)
+	b.updateID = d.UpdateID
+	b.time = ts
+	return nil
+}
+
+// applyLevels upserts price levels, removing those with a zero size
+func applyLevels(side map[string]string, levels [][2]string) {
+	for _, lvl := range levels {
+		price, size := lvl[0], lvl[1]
+		if s, err := strconv.ParseFloat(size, 64); err == nil && s == 0 {
+			delete(side, price)
+			continue
+		}
+		side[price] = size
+	}
+}
+
+// snapshot returns a sorted copy of the book trimmed to its depth
+func (b *localBook) snapshot() OrderBook {
+	book := OrderBook{
+		Symbol:   b.symbol,
+		Base:     b.base,
+		Quote:    b.quote,
+		Depth:    b.depth,
+		Bids:     sortedLevels(b.bids, true, b.depth),
+		Asks:     sortedLevels(b.asks, false, b.depth),
+		UpdateID: b.updateID,
+		Time:     b.time,
+	}
+	fillBookSummary(&book)
+	return book
+}
+
+// sortedLevels orders one side of the book, best price first
+func sortedLevels(side map[string]string, desc bool, limit int) []OrderBookLevel {
+	type level struct {
+		key   float64
+		price string
+		size  string
+	}
+	levels := make([]level, 0, len(side))
+	for price, size := range side {
+		key, err := strconv.ParseFloat(price, 64)
+		if err != nil {
+			continue
+		}
+		levels = append(levels, level{key: key, price: price, size: size})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if desc {
+			return levels[i].key > levels[j].key
+		}
+		return levels[i].key < levels[j].key
+	})
+	if limit > 0 && len(levels) > limit {
+		levels = levels[:limit]
+	}
+	out := make([]OrderBookLevel, len(levels))
+	for i, l := range levels {
+		out[i] = OrderBookLevel{Price: l.price, Size: l.size}
+	}
+	return out
+}
+
+// fillBookSummary sets best bid/ask and the spread of a sorted book
+func fillBookSummary(book *OrderBook) {
+	if len(book.Bids) > 0 {
+		book.BestBid = book.Bids[0].Price
+	}
+	if len(book.Asks) > 0 {
+		book.BestAsk = book.Asks[0].Price
+	}
+	if book.BestBid == "" || book.BestAsk == "" {
+		return
+	}
+	bid, err1 := parseDecimal(book.BestBid)
+	ask, err2 := parseDecimal(book.BestAsk)
+	if err1 == nil && err2 == nil {
+		book.Spread = new(big.Rat).Sub(ask, bid).FloatString(decimalPlaces(book.BestAsk, book.BestBid))
+	}
+}
+
+// decimalPlaces returns the largest number of fractional digits among values
+func decimalPlaces(values ...string) int {
+	places := 0
+	for _, v := range values {
+		if i := strings.IndexByte(v, '.'); i >= 0 && len(v)-i-1 > places {
+			places = len(v) - i - 1
+		}
+	}
+	return places
+}
+
+// getOrderBook fetches a one-off book snapshot from the REST host rest via
+// /v5/market/orderbook
+func getOrderBook(ctx context.Context, rest, symbol string, depth int) (*OrderBook, error) {
+	inst, err := instrumentsFor(rest).lookup(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("category", "spot")
+	q.Set("symbol", inst.Symbol)
+	q.Set("limit", strconv.Itoa(depth))
+	reqURL := rest + "/v5/market/orderbook?" + q.Encode()
+
+	var data bookData
+	err = withRetry(ctx, func() error {
+		resp, err := publicGet(ctx, reqURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return decodeEnvelope(resp, "/v5/market/orderbook", &data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	book := newLocalBook(inst, depth)
+	book.applySnapshot(data, data.Ts)
+	snap := book.snapshot()
+	return &snap, nil
+}
@@ -0,0 +1,534 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order sides, types and time-in-force values accepted by Bybit spot
+const (
+	SideBuy  = "Buy"
+	SideSell = "Sell"
+
+	OrderTypeMarket = "Market"
+	OrderTypeLimit  = "Limit"
+
+	TimeInForceGTC      = "GTC"
+	TimeInForceIOC      = "IOC"
+	TimeInForceFOK      = "FOK"
+	TimeInForcePostOnly = "PostOnly"
+
+	MarketUnitBase  = "baseCoin"
+	MarketUnitQuote = "quoteCoin"
+)
+
+// OrderRequest describes a new spot order
+type OrderRequest struct {
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	OrderType   string `json:"orderType"`
+	Qty         string `json:"qty"`
+	Price       string `json:"price,omitempty"`
+	TimeInForce string `json:"timeInForce,omitempty"`
+	// OrderLinkID is a client idempotency key; Bybit rejects a second order
+	// with the same id. Generated when empty.
+	OrderLinkID string `json:"orderLinkId,omitempty"`
+	// MarketUnit tells whether Qty of a market order is in base or quote coin.
+	// Defaults to base coin so Qty means the same thing for every order type.
+	MarketUnit string `json:"marketUnit,omitempty"`
+}
+
+// AmendOrderRequest changes the quantity and/or price of an open order.
+// Either OrderID or OrderLinkID identifies the order.
+type AmendOrderRequest struct {
+	Symbol      string `json:"symbol"`
+	OrderID     string `json:"orderId,omitempty"`
+	OrderLinkID string `json:"orderLinkId,omitempty"`
+	Qty         string `json:"qty,omitempty"`
+	Price       string `json:"price,omitempty"`
+}
+
+// OrderResult identifies an order accepted by Bybit
+type OrderResult struct {
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+}
+
+// placeOrder validates req against the instrument rules and submits it
+func (s *BybitService) placeOrder(ctx context.Context, userID string, req OrderRequest) (*OrderResult, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeOrderRequest(&req); err != nil {
+		return nil, err
+	}
+	inst, err := instrumentsFor(client.baseURL).lookup(ctx, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOrder(req, inst); err != nil {
+		return nil, err
+	}
+
+	body := map[string]string{
+		"category":    "spot",
+		"symbol":      req.Symbol,
+		"side":        req.Side,
+		"orderType":   req.OrderType,
+		"qty":         req.Qty,
+		"timeInForce": req.TimeInForce,
+		"orderLinkId": req.OrderLinkID,
+	}
+	if req.OrderType == OrderTypeLimit {
+		body["price"] = req.Price
+	} else {
+		body["marketUnit"] = req.MarketUnit
+	}
+
+	var result OrderResult
+	if err := client.post(ctx, "/v5/order/create", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// amendOrder validates the new qty/price and amends an open order
+func (s *BybitService) amendOrder(ctx context.Context, userID string, req AmendOrderRequest) (*OrderResult, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if req.Symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	if req.OrderID == "" && req.OrderLinkID == "" {
+		return nil, fmt.Errorf("orderId or orderLinkId is required")
+	}
+	if req.Qty == "" && req.Price == "" {
+		return nil, fmt.Errorf("qty or price must be changed")
+	}
+	inst, err := instrumentsFor(client.baseURL).lookup(ctx, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if req.Qty != "" {
+		if _, err := validateQty(req.Qty, inst); err != nil {
+			return nil, err
+		}
+	}
+	if req.Price != "" {
+		if _, err := validatePrice(req.Price, inst); err != nil {
+			return nil, err
+		}
+	}
+
+	body := map[string]string{
+		"category": "spot",
+		"symbol":   req.Symbol,
+	}
+	setOrderRef(body, req.OrderID, req.OrderLinkID)
+	if req.Qty != "" {
+		body["qty"] = req.Qty
+	}
+	if req.Price != "" {
+		body["price"] = req.Price
+	}
+
+	var result OrderResult
+	if err := client.post(ctx, "/v5/order/amend", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// cancelOrder cancels a single open order by orderId or orderLinkId
+func (s *BybitService) cancelOrder(ctx context.Context, userID, symbol, orderID, orderLinkID string) (*OrderResult, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	if orderID == "" && orderLinkID == "" {
+		return nil, fmt.Errorf("orderId or orderLinkId is required")
+	}
+
+	body := map[string]string{
+		"category": "spot",
+		"symbol":   symbol,
+	}
+	setOrderRef(body, orderID, orderLinkID)
+
+	var result OrderResult
+	if err := client.post(ctx, "/v5/order/cancel", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// cancelAllOrders cancels every open spot order, optionally limited to one symbol
+func (s *BybitService) cancelAllOrders(ctx context.Context, userID, symbol string) ([]OrderResult, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]string{
+		"category": "spot",
+	}
+	if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+		body["symbol"] = symbol
+	}
+
+	var result struct {
+		List []OrderResult `json:"list"`
+	}
+	if err := client.post(ctx, "/v5/order/cancel-all", body, &result); err != nil {
+		return nil, err
+	}
+	return result.List, nil
+}
+
+// setOrderRef identifies an order by orderId, falling back to orderLinkId
+func setOrderRef(body map[string]string, orderID, orderLinkID string) {
+	if orderID != "" {
+		body["orderId"] = orderID
+	} else {
+		body["orderLinkId"] = orderLinkID
+	}
+}
+
+// normalizeOrderRequest canonicalizes casing and fills defaults
+func normalizeOrderRequest(req *OrderRequest) error {
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if req.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+
+	switch strings.ToLower(req.Side) {
+	case "buy":
+		req.Side = SideBuy
+	case "sell":
+		req.Side = SideSell
+	default:
+		return fmt.Errorf("invalid side %q", req.Side)
+	}
+
+	switch strings.ToLower(req.OrderType) {
+	case "market":
+		req.OrderType = OrderTypeMarket
+		if req.TimeInForce == "" {
+			req.TimeInForce = TimeInForceIOC
+		}
+		switch req.MarketUnit {
+		case "":
+			req.MarketUnit = MarketUnitBase
+		case MarketUnitBase, MarketUnitQuote:
+		default:
+			return fmt.Errorf("invalid marketUnit %q", req.MarketUnit)
+		}
+	case "limit":
+		req.OrderType = OrderTypeLimit
+		if req.TimeInForce == "" {
+			req.TimeInForce = TimeInForceGTC
+		}
+	default:
+		return fmt.Errorf("invalid orderType %q", req.OrderType)
+	}
+
+	switch strings.ToUpper(req.TimeInForce) {
+	case TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+		req.TimeInForce = strings.ToUpper(req.TimeInForce)
+	case strings.ToUpper(TimeInForcePostOnly):
+		req.TimeInForce = TimeInForcePostOnly
+	default:
+		return fmt.Errorf("invalid timeInForce %q", req.TimeInForce)
+	}
+	if req.OrderType == OrderTypeMarket && req.TimeInForce == TimeInForcePostOnly {
+		return fmt.Errorf("market orders cannot be PostOnly")
+	}
+
+	if req.OrderLinkID == "" {
+		req.OrderLinkID = uuid.NewString()
+	}
+	if len(req.OrderLinkID) > 36 {
+		return fmt.Errorf("orderLinkId must be at most 36 characters")
+	}
+	return nil
+}
+
+// validateOrder checks a normalized order against the instrument's lot and tick sizes
+func validateOrder(req OrderRequest, inst *Instrument) error {
+	if !inst.IsTrading() {
+		return fmt.Errorf("%s is not trading (status %s)", inst.Symbol, inst.Status)
+	}
+
+	if req.OrderType == OrderTypeMarket {
+		if req.MarketUnit == MarketUnitQuote {
+			amount, err := validateAmount(req.Qty, inst.QuoteStep)
+			if err != nil {
+				return err
+			}
+			return checkMinNotional(amount, inst)
+		}
+		_, err := validateQty(req.Qty, inst)
+		return err
+	}
+
+	qty, err := validateQty(req.Qty, inst)
+	if err != nil {
+		return err
+	}
+	price, err := validatePrice(req.Price, inst)
+	if err != nil {
+		return err
+	}
+	return checkMinNotional(new(big.Rat).Mul(qty, price), inst)
+}
+
+// checkMinNotional rejects an order value (in the quote coin) below the instrument's minimum
+func checkMinNotional(value *big.Rat, inst *Instrument) error {
+	if inst.MinNotional == "" {
+		return nil
+	}
+	minNotional, err := parseDecimal(inst.MinNotional)
+	if err == nil && value.Cmp(minNotional) < 0 {
+		return fmt.Errorf("order value is below the minimum of %s %s", inst.MinNotional, inst.QuoteCoin)
+	}
+	return nil
+}
+
+// validateAmount checks that a positive amount is a multiple of step
+func validateAmount(value string, step string) (*big.Rat, error) {
+	v, err := parseDecimal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid qty: %w", err)
+	}
+	if v.Sign() <= 0 {
+		return nil, fmt.Errorf("qty must be positive")
+	}
+	if step != "" {
+		s, err := parseDecimal(step)
+		if err == nil && !isMultipleOf(v, s) {
+			return nil, fmt.Errorf("qty %s must be a multiple of %s", value, step)
+		}
+	}
+	return v, nil
+}
+
+// validateQty checks a base coin quantity against lot size and min/max order qty
+func validateQty(value string, inst *Instrument) (*big.Rat, error) {
+	qty, err := validateAmount(value, inst.LotSize)
+	if err != nil {
+		return nil, err
+	}
+	if inst.MinOrderQty != "" {
+		if minQty, err := parseDecimal(inst.MinOrderQty); err == nil && qty.Cmp(minQty) < 0 {
+			return nil, fmt.Errorf("qty %s is below the minimum of %s", value, inst.MinOrderQty)
+		}
+	}
+	if inst.MaxOrderQty != "" {
+		if maxQty, err := parseDecimal(inst.MaxOrderQty); err == nil && maxQty.Sign() > 0 && qty.Cmp(maxQty) > 0 {
+			return nil, fmt.Errorf("qty %s is above the maximum of %s", value, inst.MaxOrderQty)
+		}
+	}
+	return qty, nil
+}
+
+// validatePrice checks a limit price against the tick size
+func validatePrice(value string, inst *Instrument) (*big.Rat, error) {
+	if value == "" {
+		return nil, fmt.Errorf("price is required for limit orders")
+	}
+	price, err := parseDecimal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price: %w", err)
+	}
+	if price.Sign() <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+	if inst.TickSize != "" {
+		tick, err := parseDecimal(inst.TickSize)
+		if err == nil && !isMultipleOf(price, tick) {
+			return nil, fmt.Errorf("price %s must be a multiple of tick size %s", value, inst.TickSize)
+		}
+	}
+	return price, nil
+}
+
+// orderRecord mirrors an entry of /v5/order/realtime and /v5/order/history
+type orderRecord struct {
+	OrderID      string `json:"orderId"`
+	OrderLinkID  string `json:"orderLinkId"`
+	Symbol       string `json:"symbol"`
+	Side         string `json:"side"`
+	OrderType    string `json:"orderType"`
+	OrderStatus  string `json:"orderStatus"`
+	TimeInForce  string `json:"timeInForce"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	AvgPrice     string `json:"avgPrice"`
+	LeavesQty    string `json:"leavesQty"`
+	CumExecQty   string `json:"cumExecQty"`
+	CumExecValue string `json:"cumExecValue"`
+	CumExecFee   string `json:"cumExecFee"`
+	CreatedTime  string `json:"createdTime"`
+	UpdatedTime  string `json:"updatedTime"`
+}
+
+func (r orderRecord) toOrder() Order {
+	return Order{
+		OrderID:      r.OrderID,
+		OrderLinkID:  r.OrderLinkID,
+		Symbol:       r.Symbol,
+		Side:         r.Side,
+		OrderType:    r.OrderType,
+		OrderStatus:  r.OrderStatus,
+		TimeInForce:  r.TimeInForce,
+		Price:        r.Price,
+		Qty:          r.Qty,
+		AvgPrice:     r.AvgPrice,
+		LeavesQty:    r.LeavesQty,
+		CumExecQty:   r.CumExecQty,
+		CumExecValue: r.CumExecValue,
+		CumExecFee:   r.CumExecFee,
+		CreatedTime:  parseMillis(r.CreatedTime),
+		UpdatedTime:  parseMillis(r.UpdatedTime),
+	}
+}
+
+const (
+	// orderPageSize is the largest page Bybit returns for order queries
+	orderPageSize = "50"
+	// orderHistoryWindow is the widest startTime..endTime span /v5/order/history accepts
+	orderHistoryWindow = 7 * 24 * time.Hour
+)
+
+// getOpenOrders lists open spot orders via /v5/order/realtime
+func (s *BybitService) getOpenOrders(ctx context.Context, userID string, filter OrderFilter) ([]Order, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	q := orderFilterQuery(filter)
+	// realtime does not filter by status or time server-side
+	q.Del("orderStatus")
+	q.Del("startTime")
+	q.Del("endTime")
+
+	records, err := getAllPages[orderRecord](ctx, client, "/v5/order/realtime", q, 0)
+	if err != nil {
+		return nil, err
+	}
+	return filterOrders(records, filter), nil
+}
+
+// getOrderHistory lists closed and open spot orders via /v5/order/history
+func (s *BybitService) getOrderHistory(ctx context.Context, userID string, filter OrderFilter) ([]Order, error) {
+	client, err := s.newPrivateClient(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a range Bybit returns the last 7 days
+	if filter.StartTime <= 0 && filter.EndTime <= 0 {
+		records, err := getAllPages[orderRecord](ctx, client, "/v5/order/history", orderFilterQuery(filter), filter.Limit)
+		if err != nil {
+			return nil, err
+		}
+		return filterOrders(records, filter), nil
+	}
+
+	end := time.Now()
+	if filter.EndTime > 0 {
+		end = time.UnixMilli(filter.EndTime)
+	}
+	start := end.Add(-orderHistoryWindow)
+	if filter.StartTime > 0 {
+		start = time.UnixMilli(filter.StartTime)
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("startTime must not be after endTime")
+	}
+
+	// Walk 7-day windows newest first, matching the order Bybit returns
+	var records []orderRecord
+	for windowEnd := end; !windowEnd.Before(start); windowEnd = windowEnd.Add(-orderHistoryWindow) {
+		windowStart := windowEnd.Add(-orderHistoryWindow + time.Millisecond)
+		if windowStart.Before(start) {
+			windowStart = start
+		}
+
+		window := filter
+		window.StartTime = windowStart.UnixMilli()
+		window.EndTime = windowEnd.UnixMilli()
+		limit := 0
+		if filter.Limit > 0 {
+			limit = filter.Limit - len(records)
+		}
+
+		page, err := getAllPages[orderRecord](ctx, client, "/v5/order/history", orderFilterQuery(window), limit)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page...)
+		if filter.Limit > 0 && len(records) >= filter.Limit {
+			break
+		}
+	}
+	return filterOrders(records, filter), nil
+}
+
+// orderFilterQuery builds the query parameters shared by order endpoints
+func orderFilterQuery(filter OrderFilter) url.Values {
+	q := url.Values{}
+	q.Set("category", "spot")
+	q.Set("limit", orderPageSize)
+	if filter.Symbol != "" {
+		q.Set("symbol", strings.ToUpper(filter.Symbol))
+	}
+	if filter.Status != "" {
+		q.Set("orderStatus", filter.Status)
+	}
+	if filter.StartTime > 0 {
+		q.Set("startTime", strconv.FormatInt(filter.StartTime, 10))
+	}
+	if filter.EndTime > 0 {
+		q.Set("endTime", strconv.FormatInt(filter.EndTime, 10))
+	}
+	return q
+}
+
+// filterOrders converts records and applies the filters not enforced server-side
+func filterOrders(records []orderRecord, filter OrderFilter) []Order {
+	orders := make([]Order, 0, len(records))
+	for _, r := range records {
+		o := r.toOrder()
+		if filter.Status != "" && !strings.EqualFold(o.OrderStatus, filter.Status) {
+			continue
+		}
+		if filter.StartTime > 0 && o.CreatedTime < filter.StartTime {
+			continue
+		}
+		if filter.EndTime > 0 && o.CreatedTime > filter.EndTime {
+			continue
+		}
+		orders = append(orders, o)
+		if filter.Limit > 0 && len(orders) >= filter.Limit {
+			break
+		}
+	}
+	return orders
+}
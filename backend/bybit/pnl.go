@@ -0,0 +1,574 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CostBasisMethod selects which acquisitions a disposal is matched against
+type CostBasisMethod string
+
+const (
+	// CostBasisFIFO matches disposals against the oldest acquisitions first
+	CostBasisFIFO CostBasisMethod = "fifo"
+	// CostBasisLIFO matches disposals against the newest acquisitions first
+	CostBasisLIFO CostBasisMethod = "lifo"
+	// CostBasisAverage pools all acquisitions of a coin at their average cost
+	CostBasisAverage CostBasisMethod = "average"
+)
+
+// CoinPnl is the profit and loss of one coin in the report currency
+type CoinPnl struct {
+	Coin       string `json:"coin"`
+	Amount     string `json:"amount"`    // still held according to the fills
+	CostBasis  string `json:"costBasis"` // cost of the amount still held
+	AvgCost    string `json:"avgCost"`   // cost basis per coin
+	Price      string `json:"price"`     // current price, empty when unpriced
+	Value      string `json:"value"`
+	Realized   string `json:"realized"`
+	Unrealized string `json:"unrealized"`
+	Total      string `json:"total"`
+	FeesPaid   string `json:"feesPaid"` // fees of trades in this coin
+	// UnmatchedQty was sold without a recorded acquisition (e.g. a deposit).
+	// It is treated as sold at cost.
+	UnmatchedQty string `json:"unmatchedQty,omitempty"`
+}
+
+// PnlReport is the realized and unrealized P&L of a user's spot trades
+type PnlReport struct {
+	Method     CostBasisMethod `json:"method"`
+	Quote      string          `json:"quote"`
+	Coins      []CoinPnl       `json:"coins"`
+	CostBasis  string          `json:"costBasis"`
+	Value      string          `json:"value"`
+	Realized   string          `json:"realized"`
+	Unrealized string          `json:"unrealized"`
+	Total      string          `json:"total"`
+	FeesPaid   string          `json:"feesPaid"`
+	Unpriced   []string        `json:"unpriced,omitempty"` // held coins left out of value and unrealized
+	Executions int             `json:"executions"`
+	Skipped    []SkippedFill   `json:"skipped,omitempty"` // fills left out of every figure
+	Syncing    bool            `json:"syncing,omitempty"` // fills are still being fetched, see executionSyncEvent
+	Time       int64           `json:"time"`
+}
+
+// SkippedFill is a fill the P&L could not value, e.g. because no historical
+// rate of its quote or fee coin exists at its execution time
+type SkippedFill struct {
+	ExecID   string `json:"execId"`
+	Symbol   string `json:"symbol"`
+	ExecTime int64  `json:"execTime"`
+	Reason   string `json:"reason"`
+}
+
+// rateSource prices one unit of from in to at a point in time (ms)
+type rateSource interface {
+	rateAt(from, to string, at int64) (*big.Rat, error)
+}
+
+// costLot is an acquisition not yet disposed of
+type costLot struct {
+	qty  *big.Rat
+	cost *big.Rat
+}
+
+// coinLedger tracks the open lots and realized P&L of one coin
+type coinLedger struct {
+	coin      string
+	lots      []costLot // oldest first; a single pooled lot for average cost
+	realized  *big.Rat
+	fees      *big.Rat
+	unmatched *big.Rat
+}
+
+// pnlBook replays fills into per-coin ledgers valued in one asset. Fills are
+// valued with history, the open lots with current.
+type pnlBook struct {
+	method  CostBasisMethod
+	asset   string
+	history rateSource
+	current *rateTable
+	ledgers map[string]*coinLedger
+	skipped []SkippedFill
+}
+
+func newPnlBook(method CostBasisMethod, asset string, history rateSource, current *rateTable) *pnlBook {
+	return &pnlBook{
+		method:  method,
+		asset:   asset,
+		history: history,
+		current: current,
+		ledgers: make(map[string]*coinLedger),
+	}
+}
+
+func (b *pnlBook) ledger(coin string) *coinLedger {
+	l, ok := b.ledgers[coin]
+	if !ok {
+		l = &coinLedger{
+			coin:      coin,
+			realized:  new(big.Rat),
+			fees:      new(big.Rat),
+			unmatched: new(big.Rat),
+		}
+		b.ledgers[coin] = l
+	}
+	return l
+}
+
+// acquire adds qty of coin at cost. The valuation asset itself is money and
+// has no cost basis.
+func (b *pnlBook) acquire(coin string, qty, cost *big.Rat) {
+	if coin == b.asset || qty.Sign() <= 0 {
+		return
+	}
+	l := b.ledger(coin)
+	if b.method == CostBasisAverage && len(l.lots) > 0 {
+		l.lots[0].qty.Add(l.lots[0].qty, qty)
+		l.lots[0].cost.Add(l.lots[0].cost, cost)
+		return
+	}
+	l.lots = append(l.lots, costLot{qty: new(big.Rat).Set(qty), cost: new(big.Rat).Set(cost)})
+}
+
+// dispose removes qty of coin for proceeds and realizes the difference to its
+// cost basis. Quantity without matching lots realizes nothing.
+func (b *pnlBook) dispose(coin string, qty, proceeds *big.Rat) {
+	if coin == b.asset || qty.Sign() <= 0 {
+		return
+	}
+	l := b.ledger(coin)
+
+	remaining := new(big.Rat).Set(qty)
+	basis := new(big.Rat)
+	for remaining.Sign() > 0 && len(l.lots) > 0 {
+		idx := 0
+		if b.method == CostBasisLIFO {
+			idx = len(l.lots) - 1
+		}
+		lot := &l.lots[idx]
+		take := remaining
+		if lot.qty.Cmp(remaining) < 0 {
+			take = lot.qty
+		}
+		part := new(big.Rat).Mul(lot.cost, new(big.Rat).Quo(take, lot.qty))
+		basis.Add(basis, part)
+		remaining = new(big.Rat).Sub(remaining, take)
+		lot.cost.Sub(lot.cost, part)
+		lot.qty.Sub(lot.qty, take)
+		if lot.qty.Sign() == 0 {
+			l.lots = append(l.lots[:idx], l.lots[idx+1:]...)
+		}
+	}
+
+	matched := new(big.Rat).Set(proceeds)
+	if remaining.Sign() > 0 {
+		l.unmatched.Add(l.unmatched, remaining)
+		matched.Mul(matched, new(big.Rat).Quo(new(big.Rat).Sub(qty, remaining), qty))
+	}
+	l.realized.Add(l.realized, matched.Sub(matched, basis))
+}
+
+// apply books one fill. A buy acquires the base coin and disposes of the quote
+// coin, a sell the reverse. Fees are handled by the coin they were paid in:
+//   - base: the buyer receives less, the seller gives up more
+//   - quote: added to the cost of a buy, deducted from the proceeds of a sell
+//   - any other coin: valued at its rate, treated like a quote fee, and the
+//     fee coin itself is disposed of
+//
+// The quote and fee coins are converted to the valuation asset at their rate
+// at the execution time. Nothing is booked when a rate is missing.
+func (b *pnlBook) apply(e Execution, base, quote string) error {
+	price, err := parseDecimal(e.Price)
+	if err != nil {
+		return err
+	}
+	qty, err := parseDecimal(e.Qty)
+	if err != nil {
+		return err
+	}
+	fee := new(big.Rat)
+	if e.Fee != "" {
+		if fee, err = parseDecimal(e.Fee); err != nil {
+			return err
+		}
+	}
+	feeCoin := strings.ToUpper(e.FeeCurrency)
+	if fee.Sign() == 0 {
+		feeCoin = ""
+	}
+
+	// Value of the quote amount in the valuation asset
+	quoteRate, err := b.history.rateAt(quote, b.asset, e.ExecTime)
+	if err != nil {
+		return err
+	}
+	amount := new(big.Rat).Mul(qty, price)
+	value := new(big.Rat).Mul(amount, quoteRate)
+
+	feeValue := new(big.Rat)
+	switch feeCoin {
+	case "", base:
+	case quote:
+		feeValue.Mul(fee, quoteRate)
+	default:
+		feeRate, err := b.history.rateAt(feeCoin, b.asset, e.ExecTime)
+		if err != nil {
+			return err
+		}
+		feeValue.Mul(fee, feeRate)
+		b.dispose(feeCoin, fee, feeValue)
+	}
+
+	baseLedger := b.ledger(base)
+	if feeCoin == base {
+		baseFeeValue := new(big.Rat).Mul(fee, price)
+		baseLedger.fees.Add(baseLedger.fees, baseFeeValue.Mul(baseFeeValue, quoteRate))
+	} else {
+		baseLedger.fees.Add(baseLedger.fees, feeValue)
+	}
+
+	if strings.EqualFold(e.Side, SideBuy) {
+		received := new(big.Rat).Set(qty)
+		paid := new(big.Rat).Set(amount)
+		switch feeCoin {
+		case base:
+			received.Sub(received, fee)
+		case quote:
+			paid.Add(paid, fee)
+		}
+		cost := new(big.Rat).Add(value, feeValue)
+		b.acquire(base, received, cost)
+		b.dispose(quote, paid, new(big.Rat).Mul(paid, quoteRate))
+		return nil
+	}
+
+	given := new(big.Rat).Set(qty)
+	received := new(big.Rat).Set(amount)
+	switch feeCoin {
+	case base:
+		given.Add(given, fee)
+	case quote:
+		received.Sub(received, fee)
+	}
+	proceeds := new(big.Rat).Sub(value, feeValue)
+	b.dispose(base, given, proceeds)
+	b.acquire(quote, received, new(big.Rat).Mul(received, quoteRate))
+	return nil
+}
+
+// skip records a fill that was left out of the report
+func (b *pnlBook) skip(e Execution, reason string) {
+	b.skipped = append(b.skipped, SkippedFill{
+		ExecID:   e.ExecID,
+		Symbol:   e.Symbol,
+		ExecTime: e.ExecTime,
+		Reason:   reason,
+	})
+}
+
+// report values the open lots at current rates and totals the ledgers
+func (b *pnlBook) report(quote string) *PnlReport {
+	places := valuePlaces(quote)
+	out := &PnlReport{
+		Method: b.method,
+		Quote:  quote,
+		Coins:  []CoinPnl{},
+		Time:   time.Now().UnixMilli(),
+	}
+
+	type valued struct {
+		pnl   CoinPnl
+		value *big.Rat
+	}
+	var coins []valued
+	costTotal, valueTotal := new(big.Rat), new(big.Rat)
+	realizedTotal, unrealizedTotal, feesTotal := new(big.Rat), new(big.Rat), new(big.Rat)
+
+	for coin, l := range b.ledgers {
+		if coin == b.asset {
+			continue
+		}
+		amount, cost := new(big.Rat), new(big.Rat)
+		for _, lot := range l.lots {
+			amount.Add(amount, lot.qty)
+			cost.Add(cost, lot.cost)
+		}
+		if amount.Sign() == 0 && l.realized.Sign() == 0 && l.fees.Sign() == 0 && l.unmatched.Sign() == 0 {
+			continue
+		}
+
+		pnl := CoinPnl{
+			Coin:      coin,
+			Amount:    trimDecimal(amount.FloatString(18)),
+			CostBasis: cost.FloatString(places),
+			AvgCost:   "0",
+			Realized:  l.realized.FloatString(places),
+			FeesPaid:  l.fees.FloatString(places),
+		}
+		if amount.Sign() > 0 {
+			pnl.AvgCost = trimDecimal(new(big.Rat).Quo(cost, amount).FloatString(12))
+		}
+		if l.unmatched.Sign() > 0 {
+			pnl.UnmatchedQty = trimDecimal(l.unmatched.FloatString(18))
+		}
+
+		value, unrealized := new(big.Rat), new(big.Rat)
+		rate, _, _, ok := b.current.rate(coin, b.asset)
+		switch {
+		case ok:
+			value.Mul(amount, rate)
+			unrealized.Sub(value, cost)
+			pnl.Price = trimDecimal(rate.FloatString(12))
+		case amount.Sign() > 0:
+			out.Unpriced = append(out.Unpriced, coin)
+		}
+		pnl.Value = value.FloatString(places)
+		pnl.Unrealized = unrealized.FloatString(places)
+		pnl.Total = new(big.Rat).Add(l.realized, unrealized).FloatString(places)
+
+		if ok {
+			costTotal.Add(costTotal, cost)
+		}
+		valueTotal.Add(valueTotal, value)
+		realizedTotal.Add(realizedTotal, l.realized)
+		unrealizedTotal.Add(unrealizedTotal, unrealized)
+		feesTotal.Add(feesTotal, l.fees)
+		coins = append(coins, valued{pnl: pnl, value: value})
+	}
+
+	sort.Slice(coins, func(i, j int) bool {
+		if c := coins[i].value.Cmp(coins[j].value); c != 0 {
+			return c > 0
+		}
+		return coins[i].pnl.Coin < coins[j].pnl.Coin
+	})
+	for _, c := range coins {
+		out.Coins = append(out.Coins, c.pnl)
+	}
+	sort.Strings(out.Unpriced)
+
+	out.CostBasis = costTotal.FloatString(places)
+	out.Value = valueTotal.FloatString(places)
+	out.Realized = realizedTotal.FloatString(places)
+	out.Unrealized = unrealizedTotal.FloatString(places)
+	out.Total = new(big.Rat).Add(realizedTotal, unrealizedTotal).FloatString(places)
+	out.FeesPaid = feesTotal.FloatString(places)
+	out.Skipped = b.skipped
+	return out
+}
+
+// pnlRateInterval is the candle interval fills are priced with
+const pnlRateInterval = "60"
+
+// candleRates prices fills with the close of the hourly candle they fall in.
+// Routes are resolved per execution time: the first listed route with a
+// candle on every pair at that hour wins, so a fill from before today's
+// preferred pair was listed falls back to another quote. Candles are loaded
+// one page per symbol at a time so each load is filled inline by the store.
+type candleRates struct {
+	ctx     context.Context
+	rest    string // REST host of the user's environment
+	current *rateTable
+	closes  map[string]map[int64]*big.Rat // symbol -> candle start -> close
+	loaded  map[string]map[int64]bool     // symbol -> page start
+}
+
+func newCandleRates(ctx context.Context, rest string, current *rateTable) *candleRates {
+	return &candleRates{
+		ctx:     ctx,
+		rest:    rest,
+		current: current,
+		closes:  make(map[string]map[int64]*big.Rat),
+		loaded:  make(map[string]map[int64]bool),
+	}
+}
+
+func (c *candleRates) rateAt(from, to string, at int64) (*big.Rat, error) {
+	// The candle of the current hour is not confirmed yet
+	if at >= candleStart(time.Now().UnixMilli(), pnlRateInterval) {
+		rate, _, _, ok := c.current.rate(from, to)
+		if !ok {
+			return nil, fmt.Errorf("no rate for %s in %s", from, to)
+		}
+		return rate, nil
+	}
+	err := fmt.Errorf("no rate for %s in %s", from, to)
+	for _, route := range c.current.routes(from, to) {
+		rate, routeErr := c.routeClose(route, at)
+		if routeErr == nil {
+			return rate, nil
+		}
+		if c.ctx.Err() != nil {
+			return nil, c.ctx.Err()
+		}
+		err = routeErr
+	}
+	return nil, err
+}
+
+// routeClose multiplies the candle closes at at along route
+func (c *candleRates) routeClose(route []string, at int64) (*big.Rat, error) {
+	rate := big.NewRat(1, 1)
+	for i := 0; i+1 < len(route); i++ {
+		r, err := c.pairClose(route[i], route[i+1], at)
+		if err != nil {
+			return nil, err
+		}
+		rate.Mul(rate, r)
+	}
+	return rate, nil
+}
+
+// pairClose returns the rate from -> to over a single pair at at, inverting
+// it when only to/from is listed
+func (c *candleRates) pairClose(from, to string, at int64) (*big.Rat, error) {
+	if sym, ok := c.current.symbols[[2]string{from, to}]; ok {
+		return c.close(sym, at)
+	}
+	if sym, ok := c.current.symbols[[2]string{to, from}]; ok {
+		p, err := c.close(sym, at)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Inv(p), nil
+	}
+	return nil, fmt.Errorf("no pair for %s in %s", from, to)
+}
+
+// close returns the close of the symbol's candle containing at
+func (c *candleRates) close(symbol string, at int64) (*big.Rat, error) {
+	start := candleStart(at, pnlRateInterval)
+	page := int64(klinePageSize) * klineIntervals[pnlRateInterval].Milliseconds()
+	from := start - start%page
+	if !c.loaded[symbol][from] {
+		candles, err := getCandles(c.ctx, c.rest, symbol, pnlRateInterval, from, min(from+page-1, time.Now().UnixMilli()))
+		if err != nil {
+			return nil, fmt.Errorf("%s candles: %w", symbol, err)
+		}
+		if c.closes[symbol] == nil {
+			c.closes[symbol] = make(map[int64]*big.Rat)
+			c.loaded[symbol] = make(map[int64]bool)
+		}
+		for _, candle := range candles {
+			if p, err := parseDecimal(candle.Close); err == nil && p.Sign() > 0 {
+				c.closes[symbol][candle.Start] = p
+			}
+		}
+		c.loaded[symbol][from] = true
+	}
+	p, ok := c.closes[symbol][start]
+	if !ok {
+		return nil, fmt.Errorf("no %s candle at %s", symbol, time.UnixMilli(start).UTC().Format(time.RFC3339))
+	}
+	return p, nil
+}
+
+// normalizeCostBasisMethod validates a cost basis method, defaulting to FIFO
+func normalizeCostBasisMethod(method string) (CostBasisMethod, error) {
+	switch m := CostBasisMethod(strings.ToLower(strings.TrimSpace(method))); m {
+	case "":
+		return CostBasisFIFO, nil
+	case CostBasisFIFO, CostBasisLIFO, CostBasisAverage:
+		return m, nil
+	case "avg":
+		return CostBasisAverage, nil
+	default:
+		return "", fmt.Errorf("unsupported cost basis method %q (use fifo, lifo or average)", method)
+	}
+}
+
+// splitSymbol returns the base and quote coin of a spot symbol. Delisted
+// symbols are split on the longest known quote coin suffix.
+func splitSymbol(instruments []Instrument, symbol string) (string, string, bool) {
+	quotes := make(map[string]bool)
+	for _, inst := range instruments {
+		if inst.Symbol == symbol {
+			return inst.BaseCoin, inst.QuoteCoin, true
+		}
+		quotes[inst.QuoteCoin] = true
+	}
+	best := ""
+	for q := range quotes {
+		if len(q) > len(best) && len(q) < len(symbol) && strings.HasSuffix(symbol, q) {
+			best = q
+		}
+	}
+	if best == "" {
+		return "", "", false
+	}
+	return strings.TrimSuffix(symbol, best), best, true
+}
+
+// getPnl syncs the user's fills, or queues the sync when it is too long to
+// wait for, and replays the stored ones with method, valuing
+// everything in quote (USDT, USD, EUR or BTC)
+func (s *BybitService) getPnl(ctx context.Context, userID, method, quote string) (*PnlReport, error) {
+	costMethod, err := normalizeCostBasisMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	quote, asset, err := normalizeValuationQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+
+	syncing, err := s.syncOrQueueExecutions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	execs, err := getStoredExecutions(ctx, userID, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := s.userEndpoints(userID)
+	if err != nil {
+		return nil, err
+	}
+	instruments, err := instrumentsFor(ep.rest).list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := loadRateTable(ctx, ep.rest)
+	if err != nil {
+		return nil, err
+	}
+
+	book := newPnlBook(costMethod, asset, newCandleRates(ctx, ep.rest, rates), rates)
+	for _, e := range execs {
+		base, quoteCoin, ok := splitSymbol(instruments, e.Symbol)
+		if !ok {
+			book.skip(e, "unknown symbol")
+			continue
+		}
+		if err := book.apply(e, base, quoteCoin); err != nil {
+			book.skip(e, err.Error())
+			log.Printf("P&L skips fill %s of user %s: %v", e.ExecID, userID, err)
+		}
+	}
+
+	report := book.report(quote)
+	report.Executions = len(execs)
+	report.Syncing = syncing
+	return report, nil
+}
+
+// getCoinPnl returns the P&L of a single coin
+func (s *BybitService) getCoinPnl(ctx context.Context, userID, coin, method, quote string) (*CoinPnl, error) {
+	report, err := s.getPnl(ctx, userID, method, quote)
+	if err != nil {
+		return nil, err
+	}
+	coin = strings.ToUpper(strings.TrimSpace(coin))
+	for _, c := range report.Coins {
+		if c.Coin == coin {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("no trades found for %s", coin)
+}
@@ -0,0 +1,227 @@
+package bybit
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+// testRates prices coins in USDT by execution time; time 0 holds the rates
+// used at any time without its own entry
+type testRates map[int64]map[string]string
+
+func (r testRates) rateAt(from, to string, at int64) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if to != "USDT" {
+		return nil, fmt.Errorf("no rate for %s in %s", from, to)
+	}
+	p, ok := r[at][from]
+	if !ok {
+		p, ok = r[0][from]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no rate for %s in %s at %d", from, to, at)
+	}
+	return parseDecimal(p)
+}
+
+var testInstruments = []Instrument{
+	{Symbol: "BTCUSDT", BaseCoin: "BTC", QuoteCoin: "USDT", Status: "Trading"},
+	{Symbol: "ETHUSDT", BaseCoin: "ETH", QuoteCoin: "USDT", Status: "Trading"},
+	{Symbol: "ETHBTC", BaseCoin: "ETH", QuoteCoin: "BTC", Status: "Trading"},
+	{Symbol: "MNTUSDT", BaseCoin: "MNT", QuoteCoin: "USDT", Status: "Trading"},
+}
+
+// fill returns an execution at time at; fee is in feeCoin
+func fill(at int64, symbol, side, qty, price, fee, feeCoin string) Execution {
+	return Execution{
+		ExecID:      fmt.Sprintf("%s-%d", symbol, at),
+		Symbol:      symbol,
+		Side:        side,
+		Price:       price,
+		Qty:         qty,
+		Fee:         fee,
+		FeeCurrency: feeCoin,
+		ExecTime:    at,
+	}
+}
+
+type wantCoin struct {
+	amount, cost, realized, fees, unmatched string
+}
+
+func TestPnlBook(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      CostBasisMethod
+		rates       testRates
+		fills       []Execution
+		want        map[string]wantCoin
+		wantSkipped int
+	}{
+		{
+			name:   "fifo sells the oldest lot",
+			method: CostBasisFIFO,
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "100", "", ""),
+				fill(2, "BTCUSDT", SideBuy, "1", "200", "", ""),
+				fill(3, "BTCUSDT", SideSell, "1", "300", "", ""),
+			},
+			want: map[string]wantCoin{"BTC": {amount: "1", cost: "200.00", realized: "200.00", fees: "0.00"}},
+		},
+		{
+			name:   "lifo sells the newest lot",
+			method: CostBasisLIFO,
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "100", "", ""),
+				fill(2, "BTCUSDT", SideBuy, "1", "200", "", ""),
+				fill(3, "BTCUSDT", SideSell, "1", "300", "", ""),
+			},
+			want: map[string]wantCoin{"BTC": {amount: "1", cost: "100.00", realized: "100.00", fees: "0.00"}},
+		},
+		{
+			name:   "average sells at the pooled cost",
+			method: CostBasisAverage,
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "100", "", ""),
+				fill(2, "BTCUSDT", SideBuy, "1", "200", "", ""),
+				fill(3, "BTCUSDT", SideSell, "1", "300", "", ""),
+			},
+			want: map[string]wantCoin{"BTC": {amount: "1", cost: "150.00", realized: "150.00", fees: "0.00"}},
+		},
+		{
+			name:   "base fee on a buy reduces the amount received",
+			method: CostBasisFIFO,
+			fills: []Execution{
+				fill(1, "ETHUSDT", SideBuy, "1", "100", "0.01", "ETH"),
+				fill(2, "ETHUSDT", SideSell, "0.99", "200", "", ""),
+			},
+			// 0.99 ETH cost 100 and sold for 198
+			want: map[string]wantCoin{"ETH": {amount: "0", cost: "0.00", realized: "98.00", fees: "1.00"}},
+		},
+		{
+			name:   "base fee on a sell increases the amount given",
+			method: CostBasisFIFO,
+			fills: []Execution{
+				fill(1, "ETHUSDT", SideBuy, "1", "100", "", ""),
+				fill(2, "ETHUSDT", SideSell, "0.5", "200", "0.01", "ETH"),
+			},
+			// 0.51 ETH cost 51 and sold for 100
+			want: map[string]wantCoin{"ETH": {amount: "0.49", cost: "49.00", realized: "49.00", fees: "2.00"}},
+		},
+		{
+			name:   "quote fees are added to cost and deducted from proceeds",
+			method: CostBasisFIFO,
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "100", "1", "USDT"),
+				fill(2, "BTCUSDT", SideSell, "1", "200", "2", "USDT"),
+			},
+			want: map[string]wantCoin{"BTC": {amount: "0", cost: "0.00", realized: "97.00", fees: "3.00"}},
+		},
+		{
+			name:   "third coin fee is valued at its rate and disposed of",
+			method: CostBasisFIFO,
+			rates:  testRates{2: {"MNT": "2"}},
+			fills: []Execution{
+				fill(1, "MNTUSDT", SideBuy, "10", "1", "", ""),
+				fill(2, "BTCUSDT", SideBuy, "1", "100", "1", "MNT"),
+			},
+			want: map[string]wantCoin{
+				"BTC": {amount: "1", cost: "102.00", realized: "0.00", fees: "2.00"},
+				"MNT": {amount: "9", cost: "9.00", realized: "1.00", fees: "0.00"},
+			},
+		},
+		{
+			name:   "unmatched quantity is sold at cost",
+			method: CostBasisFIFO,
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "50", "", ""),
+				fill(2, "BTCUSDT", SideSell, "2", "100", "", ""),
+			},
+			want: map[string]wantCoin{"BTC": {amount: "0", cost: "0.00", realized: "50.00", fees: "0.00", unmatched: "1"}},
+		},
+		{
+			name:   "cross quote fill is valued at the rate of its execution time",
+			method: CostBasisFIFO,
+			rates:  testRates{1: {"BTC": "20000"}},
+			fills: []Execution{
+				fill(1, "ETHBTC", SideBuy, "1", "0.05", "", ""),
+			},
+			want: map[string]wantCoin{
+				"ETH": {amount: "1", cost: "1000.00", realized: "0.00", fees: "0.00"},
+				"BTC": {amount: "0", cost: "0.00", realized: "0.00", fees: "0.00", unmatched: "0.05"},
+			},
+		},
+		{
+			name:   "fill without a historical rate is skipped",
+			method: CostBasisFIFO,
+			rates:  testRates{1: {"BTC": "20000"}},
+			fills: []Execution{
+				fill(1, "BTCUSDT", SideBuy, "1", "100", "", ""),
+				fill(2, "ETHBTC", SideBuy, "1", "0.05", "", ""),
+				fill(3, "BTCUSDT", SideBuy, "1", "100", "1", "MNT"),
+			},
+			want:        map[string]wantCoin{"BTC": {amount: "1", cost: "100.00", realized: "0.00", fees: "0.00"}},
+			wantSkipped: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := newRateTable(testInstruments)
+			current.set("BTCUSDT", "300")
+			current.set("ETHUSDT", "200")
+			current.set("MNTUSDT", "1")
+
+			book := newPnlBook(tt.method, "USDT", tt.rates, current)
+			for _, e := range tt.fills {
+				base, quote, ok := splitSymbol(testInstruments, e.Symbol)
+				if !ok {
+					t.Fatalf("cannot split %s", e.Symbol)
+				}
+				if err := book.apply(e, base, quote); err != nil {
+					book.skip(e, err.Error())
+				}
+			}
+			report := book.report("USDT")
+
+			if len(report.Skipped) != tt.wantSkipped {
+				t.Errorf("skipped %v, want %d fills", report.Skipped, tt.wantSkipped)
+			}
+			if len(report.Coins) != len(tt.want) {
+				t.Errorf("got %d coins, want %d", len(report.Coins), len(tt.want))
+			}
+			for _, c := range report.Coins {
+				want, ok := tt.want[c.Coin]
+				if !ok {
+					t.Errorf("unexpected coin %s", c.Coin)
+					continue
+				}
+				got := wantCoin{amount: c.Amount, cost: c.CostBasis, realized: c.Realized, fees: c.FeesPaid, unmatched: c.UnmatchedQty}
+				if got != want {
+					t.Errorf("%s = %+v, want %+v", c.Coin, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateTableRoutes(t *testing.T) {
+	rates := newRateTable(testInstruments)
+	got := rates.routes("ETH", "USDT")
+	want := [][]string{{"ETH", "USDT"}, {"ETH", "BTC", "USDT"}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("routes = %v, want %v", got, want)
+	}
+
+	// Without an ETHUSDT price the rate falls back to the route through BTC
+	rates.set("BTCUSDT", "300")
+	rates.set("ETHBTC", "0.5")
+	rate, route, _, ok := rates.rate("ETH", "USDT")
+	if !ok || rate.Cmp(big.NewRat(150, 1)) != 0 || !slices.Equal(route, want[1]) {
+		t.Errorf("rate = %v over %v (%v), want 150 over %v", rate, route, ok, want[1])
+	}
+}
@@ -0,0 +1,318 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// valuationQuotes maps the supported valuation currencies to the asset that
+// prices them on Bybit spot. Bybit lists no USD pairs, so USD is valued
+// through USDC.
+var valuationQuotes = map[string]string{
+	"USDT": "USDT",
+	"USD":  "USDC",
+	"EUR":  "EUR",
+	"BTC":  "BTC",
+}
+
+// routeAssets are tried, in order, as intermediate assets for coins
+// without a direct pair against the valuation asset
+var routeAssets = []string{"USDT", "USDC", "BTC", "ETH", "EUR"}
+
+// HoldingValuation is one coin of a valued portfolio
+type HoldingValuation struct {
+	Coin       string   `json:"coin"`
+	Amount     string   `json:"amount"`
+	Price      string   `json:"price"`            // one coin in the valuation currency
+	Value      string   `json:"value"`            // amount * price
+	Allocation string   `json:"allocation"`       // percent of the total
+	Route      []string `json:"route"`            // assets the price was derived through, e.g. [ABC BTC USDT]
+	Change     string   `json:"change,omitempty"` // live stream only, see PortfolioUpdate
+}
+
+// PortfolioValuation is a user's spot portfolio valued in one currency.
+// Coins without any route to the currency are listed in Unpriced and left
+// out of the total.
+type PortfolioValuation struct {
+	Quote    string             `json:"quote"`
+	Total    string             `json:"total"`
+	Holdings []HoldingValuation `json:"holdings"`
+	Unpriced []string           `json:"unpriced,omitempty"`
+	Time     int64              `json:"time"`
+}
+
+// rateTable holds the latest spot prices and derives conversion rates
+// between any two assets through at most two intermediate assets
+type rateTable struct {
+	prices  map[string]*big.Rat  // symbol -> last price
+	symbols map[[2]string]string // {base, quote} -> symbol
+}
+
+func newRateTable(instruments []Instrument) *rateTable {
+	t := &rateTable{
+		prices:  make(map[string]*big.Rat),
+		symbols: make(map[[2]string]string, len(instruments)),
+	}
+	for _, inst := range instruments {
+		if inst.IsTrading() {
+			t.symbols[[2]string{inst.BaseCoin, inst.QuoteCoin}] = inst.Symbol
+		}
+	}
+	return t
+}
+
+// set records the last price of a symbol, ignoring empty or zero prices
+func (t *rateTable) set(symbol, price string) {
+	p, err := parseDecimal(price)
+	if err != nil || p.Sign() <= 0 {
+		return
+	}
+	t.prices[symbol] = p
+}
+
+// direct returns the rate from -> to over a single pair, inverting it when
+// only to/from is listed
+func (t *rateTable) direct(from, to string) (*big.Rat, string, bool) {
+	if sym, ok := t.symbols[[2]string{from, to}]; ok {
+		if p, ok := t.prices[sym]; ok {
+			return p, sym, true
+		}
+	}
+	if sym, ok := t.symbols[[2]string{to, from}]; ok {
+		if p, ok := t.prices[sym]; ok {
+			return new(big.Rat).Inv(p), sym, true
+		}
+	}
+	return nil, "", false
+}
+
+// listed reports whether from and to trade against each other in either
+// direction
+func (t *rateTable) listed(from, to string) bool {
+	_, ok := t.symbols[[2]string{from, to}]
+	if !ok {
+		_, ok = t.symbols[[2]string{to, from}]
+	}
+	return ok
+}
+
+// routes returns every listed path from -> to in order of preference: the
+// direct pair, then through one and then two of the routeAssets
+func (t *rateTable) routes(from, to string) [][]string {
+	if from == to {
+		return [][]string{{from}}
+	}
+	var out [][]string
+	if t.listed(from, to) {
+		out = append(out, []string{from, to})
+	}
+	for _, mid := range routeAssets {
+		if mid != from && mid != to && t.listed(from, mid) && t.listed(mid, to) {
+			out = append(out, []string{from, mid, to})
+		}
+	}
+	// e.g. ABC -> BTC -> USDT -> USDC for a coin only listed against BTC
+	for _, mid1 := range routeAssets {
+		if mid1 == from || mid1 == to || !t.listed(from, mid1) {
+			continue
+		}
+		for _, mid2 := range routeAssets {
+			if mid2 == from || mid2 == to || mid2 == mid1 {
+				continue
+			}
+			if t.listed(mid1, mid2) && t.listed(mid2, to) {
+				out = append(out, []string{from, mid1, mid2, to})
+			}
+		}
+	}
+	return out
+}
+
+// rate returns how much of to one unit of from is worth, the assets it was
+// routed through and the symbols whose prices it depends on. The first
+// route with a price on every pair wins.
+func (t *rateTable) rate(from, to string) (*big.Rat, []string, []string, bool) {
+	for _, route := range t.routes(from, to) {
+		rate := big.NewRat(1, 1)
+		var symbols []string
+		ok := true
+		for i := 0; ok && i+1 < len(route); i++ {
+			var r *big.Rat
+			var sym string
+			if r, sym, ok = t.direct(route[i], route[i+1]); ok {
+				rate.Mul(rate, r)
+				symbols = append(symbols, sym)
+			}
+		}
+		if ok {
+			return rate, route, symbols, true
+		}
+	}
+	return nil, nil, nil, false
+}
+
+// normalizeValuationQuote validates a valuation currency and returns it with
+// the asset it is priced through
+func normalizeValuationQuote(quote string) (string, string, error) {
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if quote == "" {
+		quote = "USDT"
+	}
+	asset, ok := valuationQuotes[quote]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported valuation currency %q (use USDT, USD, EUR or BTC)", quote)
+	}
+	return quote, asset, nil
+}
+
+// valuePortfolio values holdings with the prices in rates. It also returns
+// the symbols the valuation depends on.
+func valuePortfolio(holdings []Holding, quote, asset string, rates *rateTable) (*PortfolioValuation, []string) {
+	type valued struct {
+		holding HoldingValuation
+		value   *big.Rat
+	}
+
+	out := &PortfolioValuation{Quote: quote, Holdings: []HoldingValuation{}, Time: time.Now().UnixMilli()}
+	places := valuePlaces(quote)
+	total := new(big.Rat)
+	var priced []valued
+	deps := make(map[string]bool)
+
+	for _, h := range holdings {
+		amount := new(big.Rat)
+		for _, part := range []string{h.Free, h.Locked} {
+			if v, err := parseDecimal(part); err == nil {
+				amount.Add(amount, v)
+			}
+		}
+		if amount.Sign() == 0 {
+			continue
+		}
+
+		rate, route, symbols, ok := rates.rate(strings.ToUpper(h.Coin), asset)
+		if !ok {
+			out.Unpriced = append(out.Unpriced, h.Coin)
+			continue
+		}
+		for _, sym := range symbols {
+			deps[sym] = true
+		}
+		value := new(big.Rat).Mul(amount, rate)
+		total.Add(total, value)
+		priced = append(priced, valued{
+			holding: HoldingValuation{
+				Coin:   h.Coin,
+				Amount: trimDecimal(amount.FloatString(18)),
+				Price:  trimDecimal(rate.FloatString(12)),
+				Value:  value.FloatString(places),
+				Route:  route,
+			},
+			value: value,
+		})
+	}
+
+	// Largest positions first
+	sort.SliceStable(priced, func(i, j int) bool { return priced[i].value.Cmp(priced[j].value) > 0 })
+	for _, p := range priced {
+		allocation := new(big.Rat)
+		if total.Sign() > 0 {
+			allocation.Quo(new(big.Rat).Mul(p.value, big.NewRat(100, 1)), total)
+		}
+		p.holding.Allocation = allocation.FloatString(2)
+		out.Holdings = append(out.Holdings, p.holding)
+	}
+	out.Total = total.FloatString(places)
+
+	symbols := make([]string, 0, len(deps))
+	for sym := range deps {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return out, symbols
+}
+
+// valuePlaces is the number of decimals values are reported with
+func valuePlaces(quote string) int {
+	if quote == "BTC" {
+		return 8
+	}
+	return 2
+}
+
+// trimDecimal removes trailing zeros from a fixed-point decimal string
+func trimDecimal(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// getAllTickers fetches the tickers of every spot symbol on rest in a single request
+func getAllTickers(ctx context.Context, rest string) ([]tickerFields, error) {
+	q := url.Values{}
+	q.Set("category", "spot")
+	reqURL := rest + "/v5/market/tickers?" + q.Encode()
+
+	var result struct {
+		List []tickerFields `json:"list"`
+	}
+	err := withRetry(ctx, func() error {
+		resp, err := publicGet(ctx, reqURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return decodeEnvelope(resp, "/v5/market/tickers", &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.List, nil
+}
+
+// loadRateTable builds a rate table from the current spot tickers of the
+// environment served by rest
+func loadRateTable(ctx context.Context, rest string) (*rateTable, error) {
+	instruments, err := instrumentsFor(rest).list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tickers, err := getAllTickers(ctx, rest)
+	if err != nil {
+		return nil, err
+	}
+	rates := newRateTable(instruments)
+	for _, t := range tickers {
+		rates.set(t.Symbol, t.LastPrice)
+	}
+	return rates, nil
+}
+
+// getPortfolioValuation values the user's spot holdings in quote
+func (s *BybitService) getPortfolioValuation(ctx context.Context, userID, quote string) (*PortfolioValuation, error) {
+	quote, asset, err := normalizeValuationQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := s.userEndpoints(userID)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := s.getSpotHoldings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := loadRateTable(ctx, ep.rest)
+	if err != nil {
+		return nil, err
+	}
+	valuation, _ := valuePortfolio(holdings, quote, asset, rates)
+	return valuation, nil
+}
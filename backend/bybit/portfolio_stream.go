@@ -0,0 +1,455 @@
+package bybit
+
+import (
+	"coin-control/backend/exchange"
+	"context"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	portfolioUpdateEvent = "portfolio-update"
+
+	// portfolioEmitInterval caps portfolio-update events per user
+	portfolioEmitInterval = time.Second
+	// portfolioRefreshInterval re-reads holdings when no wallet push arrives
+	portfolioRefreshInterval = time.Minute
+)
+
+// PortfolioUpdate is the payload of the portfolio-update event. Change is how
+// much the price moves since the stream started tracking each coin changed the
+// value of the current amounts, so buys, deposits and withdrawals do not count.
+// It is not P&L against the cost basis; see GetPnl for that.
+type PortfolioUpdate struct {
+	UserID string `json:"userId"`
+	PortfolioValuation
+	Change        string `json:"change"`
+	ChangePercent string `json:"changePercent"`
+	Since         int64  `json:"since"` // ms, when the stream started
+}
+
+// portfolioCoin is the live valuation state of one holding
+type portfolioCoin struct {
+	coin     string
+	amount   *big.Rat
+	rate     *big.Rat // nil while no route to the valuation asset exists
+	route    []string
+	symbols  []string
+	value    *big.Rat
+	baseRate *big.Rat // rate when the coin was first priced
+}
+
+// PortfolioStream keeps a user's portfolio valued from live tickers and emits
+// throttled portfolio-update events. Price ticks only re-value the coins whose
+// route uses the ticking symbol.
+type PortfolioStream struct {
+	service *BybitService
+	ws      *WebSocketManager // public stream of the user's environment
+	userID  string
+	quote   string
+	asset   string
+	started time.Time
+
+	mu       sync.Mutex
+	rates    *rateTable
+	coins    map[string]*portfolioCoin
+	bySymbol map[string]map[string]bool // symbol -> coins priced through it
+	subs     map[string]chan PriceData  // symbol -> price subscription
+	pending  map[string]bool            // symbols being subscribed
+	total    *big.Rat
+	dirty    bool
+
+	refresh chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+var (
+	portfolioStreams   = make(map[string]*PortfolioStream)
+	portfolioStreamsMu sync.Mutex
+)
+
+// startPortfolioStream starts (or restarts with a new quote) the user's
+// portfolio stream. Starting a running stream re-emits its current state.
+func (s *BybitService) startPortfolioStream(userID, quote string) error {
+	quote, asset, err := normalizeValuationQuote(quote)
+	if err != nil {
+		return err
+	}
+	if running := runningPortfolioStream(userID, quote); running != nil {
+		running.markDirty()
+		go running.emit()
+		return nil
+	}
+
+	ep, err := s.userEndpoints(userID)
+	if err != nil {
+		return err
+	}
+
+	// Fetch outside portfolioStreamsMu; notifyHoldingsChanged must not wait on the network
+	ctx, cancel := context.WithCancel(context.Background())
+	ps := &PortfolioStream{
+		service:  s,
+		ws:       webSocketManagerFor(ep),
+		userID:   userID,
+		quote:    quote,
+		asset:    asset,
+		started:  time.Now(),
+		coins:    make(map[string]*portfolioCoin),
+		bySymbol: make(map[string]map[string]bool),
+		subs:     make(map[string]chan PriceData),
+		pending:  make(map[string]bool),
+		total:    new(big.Rat),
+		refresh:  make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	rates, err := loadRateTable(ctx, ep.rest)
+	if err != nil {
+		cancel()
+		return err
+	}
+	ps.rates = rates
+
+	holdings, err := s.getSpotHoldings(ctx, userID)
+	if err != nil {
+		cancel()
+		return err
+	}
+	ps.setHoldings(holdings)
+
+	portfolioStreamsMu.Lock()
+	previous, exists := portfolioStreams[userID]
+	if exists && previous.quote == quote {
+		// Started concurrently with the same quote; keep the running one
+		portfolioStreamsMu.Unlock()
+		ps.stop()
+		previous.markDirty()
+		return nil
+	}
+	portfolioStreams[userID] = ps
+	portfolioStreamsMu.Unlock()
+
+	if exists {
+		previous.stop()
+	}
+	go ps.run()
+	return nil
+}
+
+// runningPortfolioStream returns the user's stream if it runs with quote
+func runningPortfolioStream(userID, quote string) *PortfolioStream {
+	portfolioStreamsMu.Lock()
+	defer portfolioStreamsMu.Unlock()
+	if ps, exists := portfolioStreams[userID]; exists && ps.quote == quote {
+		return ps
+	}
+	return nil
+}
+
+// stopPortfolioStream stops the user's portfolio stream
+func stopPortfolioStream(userID string) {
+	portfolioStreamsMu.Lock()
+	ps, exists := portfolioStreams[userID]
+	delete(portfolioStreams, userID)
+	portfolioStreamsMu.Unlock()
+
+	if exists {
+		ps.stop()
+	}
+}
+
+// notifyHoldingsChanged asks the user's portfolio stream, if any, to re-read holdings
+func notifyHoldingsChanged(userID string) {
+	portfolioStreamsMu.Lock()
+	ps, exists := portfolioStreams[userID]
+	portfolioStreamsMu.Unlock()
+
+	if exists {
+		select {
+		case ps.refresh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (ps *PortfolioStream) run() {
+	emitTicker := time.NewTicker(portfolioEmitInterval)
+	defer emitTicker.Stop()
+	refreshTicker := time.NewTicker(portfolioRefreshInterval)
+	defer refreshTicker.Stop()
+
+	ps.emit()
+	for {
+		select {
+		case <-ps.ctx.Done():
+			return
+		case <-emitTicker.C:
+			ps.emit()
+		case <-refreshTicker.C:
+			ps.reloadHoldings()
+		case <-ps.refresh:
+			ps.reloadHoldings()
+		}
+	}
+}
+
+func (ps *PortfolioStream) reloadHoldings() {
+	holdings, err := ps.service.getSpotHoldings(ps.ctx, ps.userID)
+	if err != nil {
+		if ps.ctx.Err() == nil {
+			log.Printf("Portfolio stream for user %s failed to refresh holdings: %v", ps.userID, err)
+		}
+		return
+	}
+	ps.setHoldings(holdings)
+}
+
+// setHoldings adds, updates and drops coins and adjusts price subscriptions
+func (ps *PortfolioStream) setHoldings(holdings []Holding) {
+	ps.mu.Lock()
+
+	amounts := make(map[string]*big.Rat)
+	for _, h := range holdings {
+		coin := strings.ToUpper(h.Coin)
+		amount := new(big.Rat)
+		for _, part := range []string{h.Free, h.Locked} {
+			if v, err := parseDecimal(part); err == nil {
+				amount.Add(amount, v)
+			}
+		}
+		if amount.Sign() > 0 {
+			amounts[coin] = amount
+		}
+	}
+
+	for coin, c := range ps.coins {
+		if _, held := amounts[coin]; !held {
+			ps.total.Sub(ps.total, c.value)
+			ps.unindex(c)
+			delete(ps.coins, coin)
+			ps.dirty = true
+		}
+	}
+	for coin, amount := range amounts {
+		c, exists := ps.coins[coin]
+		if !exists {
+			c = &portfolioCoin{coin: coin, value: new(big.Rat)}
+			ps.coins[coin] = c
+		} else if c.amount.Cmp(amount) == 0 {
+			continue
+		}
+		c.amount = amount
+		ps.reprice(c)
+	}
+	ps.mu.Unlock()
+	ps.syncSubscriptions()
+}
+
+// reprice re-derives the rate of a coin and moves the total by the change
+// in its value. Caller must hold ps.mu.
+func (ps *PortfolioStream) reprice(c *portfolioCoin) {
+	ps.unindex(c)
+	rate, route, symbols, ok := ps.rates.rate(c.coin, ps.asset)
+	value := new(big.Rat)
+	if ok {
+		value.Mul(c.amount, rate)
+		c.rate, c.route, c.symbols = rate, route, symbols
+		if c.baseRate == nil {
+			c.baseRate = new(big.Rat).Set(rate)
+		}
+	} else {
+		c.rate, c.route, c.symbols = nil, nil, nil
+	}
+	ps.total.Add(ps.total, new(big.Rat).Sub(value, c.value))
+	c.value = value
+	ps.index(c)
+	ps.dirty = true
+}
+
+func (ps *PortfolioStream) index(c *portfolioCoin) {
+	for _, sym := range c.symbols {
+		if ps.bySymbol[sym] == nil {
+			ps.bySymbol[sym] = make(map[string]bool)
+		}
+		ps.bySymbol[sym][c.coin] = true
+	}
+}
+
+func (ps *PortfolioStream) unindex(c *portfolioCoin) {
+	for _, sym := range c.symbols {
+		delete(ps.bySymbol[sym], c.coin)
+		if len(ps.bySymbol[sym]) == 0 {
+			delete(ps.bySymbol, sym)
+		}
+	}
+}
+
+// syncSubscriptions subscribes to every symbol a route depends on and drops
+// the rest. Subscribing may look up instruments over the network, so it runs
+// without ps.mu; the caller must not hold it.
+func (ps *PortfolioStream) syncSubscriptions() {
+	ps.mu.Lock()
+	drop := make(map[string]chan PriceData)
+	for sym, ch := range ps.subs {
+		if _, needed := ps.bySymbol[sym]; !needed {
+			drop[sym] = ch
+			delete(ps.subs, sym)
+		}
+	}
+	var add []string
+	for sym := range ps.bySymbol {
+		if _, subscribed := ps.subs[sym]; !subscribed && !ps.pending[sym] {
+			ps.pending[sym] = true
+			add = append(add, sym)
+		}
+	}
+	ps.mu.Unlock()
+
+	for sym, ch := range drop {
+		ps.ws.UnsubscribeSymbol(sym, ch)
+	}
+	for _, sym := range add {
+		ch, err := ps.ws.SubscribeSymbol(sym, exchange.SubscribeOptions{Policy: exchange.PolicyCoalesce})
+
+		ps.mu.Lock()
+		delete(ps.pending, sym)
+		if err != nil {
+			ps.mu.Unlock()
+			log.Printf("Portfolio stream for user %s failed to subscribe %s: %v", ps.userID, sym, err)
+			continue
+		}
+		// Stopped, or the route changed, while subscribing
+		if _, needed := ps.bySymbol[sym]; !needed || ps.ctx.Err() != nil {
+			ps.mu.Unlock()
+			ps.ws.UnsubscribeSymbol(sym, ch)
+			continue
+		}
+		ps.subs[sym] = ch
+		ps.mu.Unlock()
+		go ps.forward(sym, ch)
+	}
+}
+
+// forward applies price ticks of one symbol until its subscription is closed
+func (ps *PortfolioStream) forward(symbol string, ch chan PriceData) {
+	for update := range ch {
+		ps.onPrice(symbol, update.Price)
+	}
+}
+
+func (ps *PortfolioStream) onPrice(symbol, price string) {
+	ps.mu.Lock()
+	ps.rates.set(symbol, price)
+	routeChanged := false
+	for coin := range ps.bySymbol[symbol] {
+		c := ps.coins[coin]
+		before := len(c.symbols)
+		ps.reprice(c)
+		routeChanged = routeChanged || len(c.symbols) != before
+	}
+	ps.mu.Unlock()
+
+	if routeChanged {
+		ps.syncSubscriptions()
+	}
+}
+
+// markDirty makes the next emit send the current state even if nothing changed
+func (ps *PortfolioStream) markDirty() {
+	ps.mu.Lock()
+	ps.dirty = true
+	ps.mu.Unlock()
+}
+
+// emit sends portfolio-update when anything changed since the last event
+func (ps *PortfolioStream) emit() {
+	ps.mu.Lock()
+	if !ps.dirty {
+		ps.mu.Unlock()
+		return
+	}
+	ps.dirty = false
+	update := ps.snapshot()
+	ps.mu.Unlock()
+
+	if ctx := getRuntimeCtx(); ctx != nil {
+		runtime.EventsEmit(ctx, portfolioUpdateEvent, update)
+	}
+}
+
+// snapshot renders the current state. Caller must hold ps.mu.
+func (ps *PortfolioStream) snapshot() PortfolioUpdate {
+	places := valuePlaces(ps.quote)
+	update := PortfolioUpdate{
+		UserID: ps.userID,
+		PortfolioValuation: PortfolioValuation{
+			Quote:    ps.quote,
+			Total:    ps.total.FloatString(places),
+			Holdings: []HoldingValuation{},
+			Time:     time.Now().UnixMilli(),
+		},
+		Since: ps.started.UnixMilli(),
+	}
+
+	coins := make([]*portfolioCoin, 0, len(ps.coins))
+	for _, c := range ps.coins {
+		if c.rate == nil {
+			update.Unpriced = append(update.Unpriced, c.coin)
+			continue
+		}
+		coins = append(coins, c)
+	}
+	sort.Strings(update.Unpriced)
+	sort.Slice(coins, func(i, j int) bool { return coins[i].value.Cmp(coins[j].value) > 0 })
+
+	change, baseline := new(big.Rat), new(big.Rat)
+	for _, c := range coins {
+		allocation := new(big.Rat)
+		if ps.total.Sign() > 0 {
+			allocation.Quo(new(big.Rat).Mul(c.value, big.NewRat(100, 1)), ps.total)
+		}
+		coinBaseline := new(big.Rat).Mul(c.amount, c.baseRate)
+		coinChange := new(big.Rat).Sub(c.value, coinBaseline)
+		change.Add(change, coinChange)
+		baseline.Add(baseline, coinBaseline)
+		update.Holdings = append(update.Holdings, HoldingValuation{
+			Coin:       c.coin,
+			Amount:     trimDecimal(c.amount.FloatString(18)),
+			Price:      trimDecimal(c.rate.FloatString(12)),
+			Value:      c.value.FloatString(places),
+			Allocation: allocation.FloatString(2),
+			Route:      c.route,
+			Change:     coinChange.FloatString(places),
+		})
+	}
+
+	update.Change = change.FloatString(places)
+	pct := new(big.Rat)
+	if baseline.Sign() > 0 {
+		pct.Quo(new(big.Rat).Mul(change, big.NewRat(100, 1)), baseline)
+	}
+	update.ChangePercent = pct.FloatString(2)
+	return update
+}
+
+func (ps *PortfolioStream) stop() {
+	ps.cancel()
+
+	ps.mu.Lock()
+	subs := ps.subs
+	ps.subs = make(map[string]chan PriceData)
+	ps.mu.Unlock()
+
+	for sym, ch := range subs {
+		ps.ws.UnsubscribeSymbol(sym, ch)
+	}
+}
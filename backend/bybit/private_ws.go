@@ -0,0 +1,400 @@
+package bybit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// privateTopics are subscribed on every private connection. They cover all
+// categories; only spot updates are forwarded.
+var privateTopics = []string{"wallet", "order", "execution"}
+
+const (
+	// authExpiry is how long the signed auth request stays valid
+	authExpiry = 10 * time.Second
+	// privateOpTimeout bounds the wait for the auth and subscribe responses
+	privateOpTimeout = 10 * time.Second
+)
+
+// PrivateStream is an authenticated WebSocket connection for a single user.
+// It pushes wallet, order and execution changes to the frontend as
+// wallet-update-<userId>, order-update-<userId> and execution-update-<userId>,
+// and its connection state as account-stream-<userId>.
+type PrivateStream struct {
+	userID      string
+	apiKey      string
+	secret      string
+	ep          endpoints
+	instruments *instrumentCache
+
+	session *wsSession // current connection, nil while disconnected
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// PrivateStreamStatus is the payload of the account-stream-<userId> event
+type PrivateStreamStatus struct {
+	State     ConnState `json:"state"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// privateMessage is the envelope of private stream messages and op responses
+type privateMessage struct {
+	Op           string          `json:"op"`
+	Success      *bool           `json:"success"`
+	RetMsg       string          `json:"ret_msg"`
+	Topic        string          `json:"topic"`
+	CreationTime int64           `json:"creationTime"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// walletRecord is an account entry of the wallet topic
+type walletRecord struct {
+	AccountType string `json:"accountType"`
+	Coin        []struct {
+		Coin          string `json:"coin"`
+		WalletBalance string `json:"walletBalance"`
+		Free          string `json:"free"`
+		Locked        string `json:"locked"`
+	} `json:"coin"`
+}
+
+var (
+	privateStreams   = make(map[string]*PrivateStream)
+	privateStreamsMu sync.Mutex
+)
+
+// startPrivateStream opens the private stream of a user if it is not running yet
+func (s *BybitService) startPrivateStream(userID string) error {
+	privateStreamsMu.Lock()
+	defer privateStreamsMu.Unlock()
+
+	if _, exists := privateStreams[userID]; exists {
+		return nil
+	}
+
+	creds, err := s.GetBybitByUserId(userID)
+	if err != nil {
+		return fmt.Errorf("bybit credentials not found: %w", err)
+	}
+	ep, err := creds.endpoints()
+	if err != nil {
+		return err
+	}
+	trackServerClock(ep.rest)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ps := &PrivateStream{
+		userID:      userID,
+		apiKey:      creds.ApiKey,
+		secret:      creds.ApiSecret,
+		ep:          ep,
+		instruments: instrumentsFor(ep.rest),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	privateStreams[userID] = ps
+	go ps.run()
+	return nil
+}
+
+// stopPrivateStream closes the private stream of a user
+func stopPrivateStream(userID string) {
+	privateStreamsMu.Lock()
+	ps, exists := privateStreams[userID]
+	delete(privateStreams, userID)
+	privateStreamsMu.Unlock()
+
+	if exists {
+		ps.close()
+	}
+}
+
+// run keeps the stream connected until it is closed
+func (ps *PrivateStream) run() {
+	attempt := 0
+	for {
+		select {
+		case <-ps.ctx.Done():
+			return
+		default:
+		}
+
+		attempt++
+		session, err := ps.connect()
+		if err != nil {
+			if ps.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Private WebSocket for user %s failed: %v", ps.userID, err)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.IsAuthError() {
+				// Bad credentials will not fix themselves; stop retrying
+				ps.emitStatus(StateClosed, err)
+				ps.remove()
+				return
+			}
+			ps.emitStatus(StateReconnecting, err)
+			if !ps.sleep(reconnectDelay(attempt)) {
+				return
+			}
+			continue
+		}
+		attempt = 0
+		ps.emitStatus(StateConnected, nil)
+		err = ps.readLoop(session)
+		if ps.ctx.Err() != nil {
+			ps.emitStatus(StateClosed, nil)
+			return
+		}
+		ps.emitStatus(StateReconnecting, err)
+		// Short jittered pause so a server dropping us right after auth is not hammered
+		if !ps.sleep(reconnectDelay(1)) {
+			return
+		}
+	}
+}
+
+// sleep waits for d and reports false when the stream was closed meanwhile
+func (ps *PrivateStream) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ps.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// remove closes the stream and unregisters it unless the user has started a
+// newer one meanwhile
+func (ps *PrivateStream) remove() {
+	privateStreamsMu.Lock()
+	if privateStreams[ps.userID] == ps {
+		delete(privateStreams, ps.userID)
+	}
+	privateStreamsMu.Unlock()
+	ps.close()
+}
+
+// connect dials, authenticates and subscribes to the private topics, then
+// hands the connection to a session for writes and pings
+func (ps *PrivateStream) connect() (*wsSession, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(ps.ep.privateWS+"/v5/private", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := strconv.FormatInt(serverNow(ps.ep.rest)+authExpiry.Milliseconds(), 10)
+	auth := map[string]interface{}{
+		"op":   "auth",
+		"args": []string{ps.apiKey, expires, signRealtime(ps.secret, expires)},
+	}
+	resp, err := privateOp(conn, auth)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("auth response: %w", err)
+	}
+	if !resp.ok("auth") {
+		conn.Close()
+		return nil, &APIError{RetCode: retCodeAuthFailed, RetMsg: resp.RetMsg, HTTPStatus: 401, Endpoint: "/v5/private"}
+	}
+
+	resp, err = privateOp(conn, map[string]interface{}{"op": "subscribe", "args": privateTopics})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribe response: %w", err)
+	}
+	if !resp.ok("subscribe") {
+		conn.Close()
+		return nil, fmt.Errorf("private subscribe failed: %s", resp.RetMsg)
+	}
+
+	session := newSession(conn)
+	ps.mu.Lock()
+	if ps.ctx.Err() != nil {
+		ps.mu.Unlock()
+		session.close()
+		return nil, errSessionClosed
+	}
+	ps.session = session
+	ps.mu.Unlock()
+
+	log.Printf("Private WebSocket connected for user %s", ps.userID)
+	return session, nil
+}
+
+// privateOp sends a request on a connection no session owns yet and reads its
+// response
+func privateOp(conn *websocket.Conn, req interface{}) (*privateMessage, error) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := conn.WriteJSON(req); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(privateOpTimeout))
+	var resp privateMessage
+	if err := conn.ReadJSON(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ok reports whether msg is a successful response to op
+func (msg *privateMessage) ok(op string) bool {
+	return msg.Op == op && msg.Success != nil && *msg.Success
+}
+
+// readLoop handles messages until the connection fails. Every message,
+// including ping responses, extends the read deadline, so a half-open
+// connection is given up after pongTimeout.
+func (ps *PrivateStream) readLoop(session *wsSession) error {
+	defer func() {
+		ps.mu.Lock()
+		if ps.session == session {
+			ps.session = nil
+		}
+		ps.mu.Unlock()
+		session.close()
+	}()
+
+	for {
+		session.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		var msg privateMessage
+		if err := session.conn.ReadJSON(&msg); err != nil {
+			if ps.ctx.Err() == nil {
+				log.Printf("Private WebSocket read error for user %s: %v", ps.userID, err)
+			}
+			return err
+		}
+
+		if msg.Op != "" {
+			continue
+		}
+
+		switch msg.Topic {
+		case "wallet":
+			ps.handleWallet(msg.Data)
+		case "order":
+			ps.handleOrders(msg.Data)
+		case "execution":
+			ps.handleExecutions(msg.Data)
+		}
+	}
+}
+
+func (ps *PrivateStream) handleWallet(data json.RawMessage) {
+	var accounts []walletRecord
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		dbg("bad wallet message: %v", err)
+		return
+	}
+
+	var holdings []Holding
+	for _, account := range accounts {
+		if account.AccountType != "UNIFIED" {
+			continue
+		}
+		for _, c := range account.Coin {
+			if c.Coin == "" {
+				continue
+			}
+			holdings = append(holdings, holdingFromWallet(c.Coin, c.WalletBalance, c.Free, c.Locked))
+		}
+	}
+	if holdings != nil {
+		ps.emit("wallet-update-"+ps.userID, holdings)
+		notifyHoldingsChanged(ps.userID)
+	}
+}
+
+func (ps *PrivateStream) handleOrders(data json.RawMessage) {
+	var records []struct {
+		Category string `json:"category"`
+		orderRecord
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		dbg("bad order message: %v", err)
+		return
+	}
+
+	var orders []Order
+	for _, r := range records {
+		if r.Category == "spot" {
+			orders = append(orders, r.toOrder())
+		}
+	}
+	if orders != nil {
+		ps.emit("order-update-"+ps.userID, orders)
+	}
+}
+
+// handleExecutions forwards fills and records them in the local trade ledger
+func (ps *PrivateStream) handleExecutions(data json.RawMessage) {
+	var records []struct {
+		Category string `json:"category"`
+		executionRecord
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		dbg("bad execution message: %v", err)
+		return
+	}
+
+	var execs []Execution
+	for _, r := range records {
+		if r.Category == "spot" {
+			execs = append(execs, r.toExecution(ps.instruments))
+		}
+	}
+	if execs == nil {
+		return
+	}
+	if err := storeExecutions(ps.ctx, ps.userID, execs); err != nil {
+		log.Printf("Failed to store streamed executions for user %s: %v", ps.userID, err)
+	}
+	ps.emit("execution-update-"+ps.userID, execs)
+}
+
+func (ps *PrivateStream) emit(event string, data interface{}) {
+	if ctx := getRuntimeCtx(); ctx != nil {
+		runtime.EventsEmit(ctx, event, data)
+	}
+}
+
+// emitStatus announces a connection state change as account-stream-<userId>
+func (ps *PrivateStream) emitStatus(state ConnState, err error) {
+	status := PrivateStreamStatus{State: state}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	ps.emit("account-stream-"+ps.userID, status)
+}
+
+func (ps *PrivateStream) close() {
+	ps.cancel()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.session != nil {
+		ps.session.close()
+	}
+}
+
+// signRealtime signs the private stream auth request
+func signRealtime(secret string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("GET/realtime" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package bybit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults used until Bybit reports the real limit through response headers
+const (
+	defaultRateLimit = 10 // requests per second
+	publicLimiterKey = "public"
+)
+
+// tokenBucket throttles requests of a single API key to a single endpoint, as
+// Bybit limits each endpoint separately. Its capacity and remaining tokens are
+// corrected from Bybit's X-Bapi-Limit* response headers.
+type tokenBucket struct {
+	mu           sync.Mutex
+	capacity     float64
+	tokens       float64
+	rate         float64 // tokens refilled per second
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newTokenBucket(perSecond float64) *tokenBucket {
+	return &tokenBucket{
+		capacity: perSecond,
+		tokens:   perSecond,
+		rate:     perSecond,
+		last:     time.Now(),
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*tokenBucket{}
+)
+
+// limiterFor returns the bucket shared by all requests made with apiKey to path
+func limiterFor(apiKey, path string) *tokenBucket {
+	if apiKey == "" {
+		apiKey = publicLimiterKey
+	}
+	key := apiKey + " " + path
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	b, ok := limiters[key]
+	if !ok {
+		b = newTokenBucket(defaultRateLimit)
+		limiters[key] = b
+	}
+	return b
+}
+
+// refill adds tokens for the time elapsed since the last call. Caller holds mu.
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// wait blocks until a token is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.refill(now)
+		var delay time.Duration
+		switch {
+		case now.Before(b.blockedUntil):
+			delay = b.blockedUntil.Sub(now)
+		case b.tokens >= 1:
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		default:
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// observe updates the bucket from Bybit's rate limit headers
+func (b *tokenBucket) observe(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-Bapi-Limit-Status"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(h.Get("X-Bapi-Limit"))
+	resetMs, _ := strconv.ParseInt(h.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit > 0 {
+		b.capacity = float64(limit)
+		b.rate = float64(limit)
+	}
+	if float64(remaining) < b.tokens {
+		b.tokens = float64(remaining)
+	}
+	if remaining <= 0 && resetMs > 0 {
+		if reset := time.UnixMilli(resetMs); reset.After(b.blockedUntil) {
+			b.blockedUntil = reset
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bybit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Retry policy for idempotent GET requests
+const (
+	retryAttempts  = 4
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 4 * time.Second
+)
+
+// httpStatusError is returned by GETs to endpoints that do not use the v5 envelope
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// newHTTPStatusError drains resp and wraps its status and body
+func newHTTPStatusError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error or
+// the attempts run out, sleeping with jittered exponential backoff in between
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < retryAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoffDelay(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+		err = fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		dbg("bybit request failed (attempt %d/%d): %v", attempt+1, retryAttempts, err)
+	}
+	return err
+}
+
+// backoffDelay returns the delay before retry attempt n of a REST request
+func backoffDelay(attempt int) time.Duration {
+	return jitteredBackoff(attempt, retryBaseDelay, retryMaxDelay)
+}
+
+// jitteredBackoff returns base doubled per attempt (1-based), capped at maxDelay,
+// with equal jitter
+func jitteredBackoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryable reports whether err is a timeout, a 5xx or a rate limit rejection
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.IsRateLimited() || apiErr.HTTPStatus >= 500
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+// publicGet sends an unsigned GET to a Bybit host, honouring the shared public rate limit
+func publicGet(ctx context.Context, reqURL string) (*http.Response, error) {
+	// Public endpoints share one per-IP limit rather than per-endpoint ones
+	limiter := limiterFor("", "")
+	if err := limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	limiter.observe(resp.Header)
+	return resp, nil
+}
@@ -0,0 +1,207 @@
+package bybit
+
+import (
+	"coin-control/backend/database"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// TaskSnapshotPortfolios stores the valuation of every Bybit user
+	TaskSnapshotPortfolios = "bybit:snapshot_portfolios"
+
+	defaultSnapshotInterval = 15 * time.Minute
+	minSnapshotInterval     = time.Minute
+)
+
+// historyRanges maps GetPortfolioHistory ranges to their length; "all" has none
+var historyRanges = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"1d":  24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+	"1y":  365 * 24 * time.Hour,
+	"all": 0,
+}
+
+// historyResolutions maps resolutions to bucket sizes; "raw" returns every snapshot
+var historyResolutions = map[string]time.Duration{
+	"raw": 0,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// PortfolioPoint is one point of a net-worth time series
+type PortfolioPoint struct {
+	Time      int64  `json:"time"` // ms
+	TotalUSDT string `json:"totalUsdt"`
+	TotalUSD  string `json:"totalUsd"`
+	TotalEUR  string `json:"totalEur"`
+	TotalBTC  string `json:"totalBtc"`
+	// Unpriced lists held coins missing from the totals, so a dip caused by
+	// a coin losing its price can be told apart from a real loss
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// snapshotInterval reads PORTFOLIO_SNAPSHOT_INTERVAL (a Go duration such as
+// "15m"), falling back to 15 minutes
+func snapshotInterval() time.Duration {
+	v := strings.TrimSpace(os.Getenv("PORTFOLIO_SNAPSHOT_INTERVAL"))
+	if v == "" {
+		return defaultSnapshotInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < minSnapshotInterval {
+		log.Printf("Invalid PORTFOLIO_SNAPSHOT_INTERVAL %q, using %s", v, defaultSnapshotInterval)
+		return defaultSnapshotInterval
+	}
+	return d
+}
+
+// handleSnapshotPortfolios values every user with Bybit credentials and
+// stores the result. Failures of single users are logged and skipped.
+func (s *BybitService) handleSnapshotPortfolios(ctx context.Context, _ *asynq.Task) error {
+	userIDs, err := listBybitUserIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Rates are loaded once per environment the users trade on
+	rateTables := make(map[string]*rateTable)
+	takenAt := time.Now().Truncate(time.Second)
+	saved := 0
+	for _, userID := range userIDs {
+		ep, err := s.userEndpoints(userID)
+		if err != nil {
+			log.Printf("Portfolio snapshot for user %s failed: %v", userID, err)
+			continue
+		}
+		rates, ok := rateTables[ep.rest]
+		if !ok {
+			if rates, err = loadRateTable(ctx, ep.rest); err != nil {
+				log.Printf("Portfolio snapshot for user %s failed: %v", userID, err)
+				continue
+			}
+			rateTables[ep.rest] = rates
+		}
+		if err := s.snapshotPortfolio(ctx, userID, rates, takenAt); err != nil {
+			log.Printf("Portfolio snapshot for user %s failed: %v", userID, err)
+			continue
+		}
+		saved++
+	}
+	dbg("Stored %d/%d portfolio snapshots", saved, len(userIDs))
+	return nil
+}
+
+func (s *BybitService) snapshotPortfolio(ctx context.Context, userID string, rates *rateTable, takenAt time.Time) error {
+	holdings, err := s.getSpotHoldings(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	totals := make(map[string]string, len(valuationQuotes))
+	unpriced := make(map[string]bool)
+	var detail *PortfolioValuation
+	for quote, asset := range valuationQuotes {
+		valuation, _ := valuePortfolio(holdings, quote, asset, rates)
+		totals[quote] = valuation.Total
+		for _, coin := range valuation.Unpriced {
+			unpriced[coin] = true
+		}
+		if quote == "USDT" {
+			detail = valuation
+		}
+	}
+	unpricedCoins := make([]string, 0, len(unpriced))
+	for coin := range unpriced {
+		unpricedCoins = append(unpricedCoins, coin)
+	}
+	sort.Strings(unpricedCoins)
+	encoded, err := json.Marshal(detail.Holdings)
+	if err != nil {
+		return err
+	}
+
+	return database.SavePortfolioSnapshot(ctx, database.PortfolioSnapshot{
+		UserID:    userID,
+		Exchange:  ExchangeName,
+		TakenAt:   takenAt,
+		TotalUSDT: totals["USDT"],
+		TotalUSD:  totals["USD"],
+		TotalEUR:  totals["EUR"],
+		TotalBTC:  totals["BTC"],
+		Holdings:  encoded,
+		Unpriced:  unpricedCoins,
+	})
+}
+
+// getPortfolioHistory returns the user's net worth over rangeName ("24h", "7d",
+// "30d", "90d", "1y", "all") at resolution ("raw", "1h", "4h", "1d", "1w").
+// An empty resolution picks one that suits the range.
+func getPortfolioHistory(ctx context.Context, userID, rangeName, resolution string) ([]PortfolioPoint, error) {
+	rangeName = strings.ToLower(strings.TrimSpace(rangeName))
+	if rangeName == "" {
+		rangeName = "30d"
+	}
+	length, ok := historyRanges[rangeName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported history range %q", rangeName)
+	}
+
+	resolution = strings.ToLower(strings.TrimSpace(resolution))
+	if resolution == "" {
+		resolution = defaultResolution(length)
+	}
+	bucket, ok := historyResolutions[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unsupported history resolution %q", resolution)
+	}
+
+	from := time.Unix(0, 0)
+	if length > 0 {
+		from = time.Now().Add(-length)
+	}
+	snapshots, err := database.GetPortfolioSnapshots(ctx, userID, ExchangeName, from, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]PortfolioPoint, 0, len(snapshots))
+	for _, snap := range snapshots {
+		points = append(points, PortfolioPoint{
+			Time:      snap.TakenAt.UnixMilli(),
+			TotalUSDT: snap.TotalUSDT,
+			TotalUSD:  snap.TotalUSD,
+			TotalEUR:  snap.TotalEUR,
+			TotalBTC:  snap.TotalBTC,
+			Unpriced:  snap.Unpriced,
+		})
+	}
+	return points, nil
+}
+
+// defaultResolution keeps a range at a few hundred points at most
+func defaultResolution(length time.Duration) string {
+	switch {
+	case length == 0 || length > 90*24*time.Hour:
+		return "1d"
+	case length > 30*24*time.Hour:
+		return "4h"
+	case length > 24*time.Hour:
+		return "1h"
+	default:
+		return "raw"
+	}
+}
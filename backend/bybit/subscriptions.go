@@ -0,0 +1,149 @@
+package bybit
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// subscribeBatchSize is the number of topics Bybit accepts per spot subscribe request
+const subscribeBatchSize = 10
+
+// TopicStatus reports whether Bybit confirmed a desired public topic
+type TopicStatus struct {
+	Topic       string `json:"topic"`
+	Confirmed   bool   `json:"confirmed"`
+	LastMessage int64  `json:"lastMessage,omitempty"` // ms
+	Stale       bool   `json:"stale"`
+}
+
+// wantTopic records a topic the manager should stay subscribed to. It is
+// subscribed right away when connected and replayed after every reconnect.
+// Caller must hold ws.mu.
+func (ws *WebSocketManager) wantTopic(topic string) {
+	if _, exists := ws.topics[topic]; exists {
+		return
+	}
+	ws.topics[topic] = false
+	if ws.isConnected {
+		ws.requestTopics("subscribe", []string{topic})
+	}
+}
+
+// dropTopic forgets a topic and unsubscribes from it when connected.
+// Caller must hold ws.mu.
+func (ws *WebSocketManager) dropTopic(topic string) {
+	if _, exists := ws.topics[topic]; !exists {
+		return
+	}
+	delete(ws.topics, topic)
+	ws.health.forget(topic)
+	if ws.isConnected {
+		ws.requestTopics("unsubscribe", []string{topic})
+	}
+}
+
+// resubscribeAll replays every desired topic on a fresh connection, in batches.
+// Caller must hold ws.mu.
+func (ws *WebSocketManager) resubscribeAll() {
+	ws.pendingReqs = make(map[string][]string)
+
+	topics := make([]string, 0, len(ws.topics))
+	for topic := range ws.topics {
+		ws.topics[topic] = false
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	// Deltas of the old connection must not be applied to the new snapshot
+	for _, book := range ws.books {
+		book.synced = false
+	}
+
+	for start := 0; start < len(topics); start += subscribeBatchSize {
+		end := min(start+subscribeBatchSize, len(topics))
+		ws.requestTopics("subscribe", topics[start:end])
+	}
+	if len(topics) > 0 {
+		log.Printf("Resubscribing %d WebSocket topic(s)", len(topics))
+	}
+}
+
+// requestTopics sends a subscribe or unsubscribe request. Subscribe requests
+// are tracked by req_id until Bybit confirms them. Caller must hold ws.mu.
+func (ws *WebSocketManager) requestTopics(op string, topics []string) {
+	if ws.session == nil {
+		return
+	}
+
+	ws.reqSeq++
+	reqID := fmt.Sprintf("%s-%d", op, ws.reqSeq)
+	if op == "subscribe" {
+		ws.pendingReqs[reqID] = topics
+	}
+
+	msg := map[string]interface{}{
+		"req_id": reqID,
+		"op":     op,
+		"args":   topics,
+	}
+	if err := ws.session.send(msg); err != nil {
+		// The read loop will see the broken connection; topics are replayed on reconnect
+		log.Printf("Failed to %s %v: %v", op, topics, err)
+		delete(ws.pendingReqs, reqID)
+	}
+}
+
+// handleOpResponse confirms subscriptions. A rejected batch is retried topic by
+// topic so one bad topic does not leave the rest of the batch unsubscribed.
+func (ws *WebSocketManager) handleOpResponse(msg wsMessage) {
+	if msg.Op == "ping" || msg.Op == "pong" {
+		ws.health.pong()
+		return
+	}
+	if msg.Op != "subscribe" {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	topics, ok := ws.pendingReqs[msg.ReqID]
+	if !ok {
+		return
+	}
+	delete(ws.pendingReqs, msg.ReqID)
+
+	if msg.Success != nil && *msg.Success {
+		for _, topic := range topics {
+			if _, wanted := ws.topics[topic]; wanted {
+				ws.topics[topic] = true
+				ws.health.touch(topic)
+			}
+		}
+		dbg("Subscribed to %v", topics)
+		return
+	}
+
+	log.Printf("Bybit rejected subscription to %v: %s", topics, msg.RetMsg)
+	if len(topics) > 1 {
+		for _, topic := range topics {
+			if _, wanted := ws.topics[topic]; wanted {
+				ws.requestTopics("subscribe", []string{topic})
+			}
+		}
+	}
+}
+
+// TopicStatuses lists the desired public topics and whether Bybit confirmed them
+func (ws *WebSocketManager) TopicStatuses() []TopicStatus {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	out := make([]TopicStatus, 0, len(ws.topics))
+	for topic, confirmed := range ws.topics {
+		out = append(out, TopicStatus{Topic: topic, Confirmed: confirmed})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Topic < out[j].Topic })
+	return out
+}
@@ -0,0 +1,31 @@
+package bybit
+
+import "coin-control/backend/exchange"
+
+type Trade = exchange.Trade
+
+// tradeRecord is a single entry of a publicTrade.{symbol} message
+type tradeRecord struct {
+	Time    int64  `json:"T"`
+	Symbol  string `json:"s"`
+	Side    string `json:"S"`
+	Size    string `json:"v"`
+	Price   string `json:"p"`
+	TradeID string `json:"i"`
+}
+
+// tradeTopic returns the public trade topic of a symbol
+func tradeTopic(symbol string) string {
+	return "publicTrade." + symbol
+}
+
+func (r tradeRecord) toTrade() Trade {
+	return Trade{
+		Symbol:  r.Symbol,
+		TradeID: r.TradeID,
+		Price:   r.Price,
+		Size:    r.Size,
+		Side:    r.Side,
+		Time:    r.Time,
+	}
+}
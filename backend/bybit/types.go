@@ -1,35 +1,67 @@
 package bybit
 
 import (
+	"coin-control/backend/exchange"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// Core data types
-type Holding struct {
-	Coin   string `json:"coin"`
-	Free   string `json:"free"`
-	Locked string `json:"locked"`
-}
+// Core data types are shared with other exchange connectors
+type Holding = exchange.Holding
 
 // Balance data structures
-type CoinBalance struct {
-	Coin            string `json:"coin"`
-	WalletBalance   string `json:"walletBalance"`
-	TransferBalance string `json:"transferBalance"`
-	Locked          string `json:"locked"`
-	Bonus           string `json:"bonus"`
+type CoinBalance = exchange.CoinBalance
+
+// Order data structures
+type Order struct {
+	OrderID      string `json:"orderId"`
+	OrderLinkID  string `json:"orderLinkId"`
+	Symbol       string `json:"symbol"`
+	Side         string `json:"side"`
+	OrderType    string `json:"orderType"`
+	OrderStatus  string `json:"orderStatus"`
+	TimeInForce  string `json:"timeInForce"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	AvgPrice     string `json:"avgPrice"`
+	LeavesQty    string `json:"leavesQty"`
+	CumExecQty   string `json:"cumExecQty"`
+	CumExecValue string `json:"cumExecValue"`
+	CumExecFee   string `json:"cumExecFee"`
+	CreatedTime  int64  `json:"createdTime"`
+	UpdatedTime  int64  `json:"updatedTime"`
+}
+
+// OrderFilter narrows open order and order history queries. Zero values mean no filter.
+type OrderFilter struct {
+	Symbol    string `json:"symbol"`
+	Status    string `json:"status"`
+	StartTime int64  `json:"startTime"` // ms
+	EndTime   int64  `json:"endTime"`   // ms
+	Limit     int    `json:"limit"`     // max orders returned across pages
+}
+
+// Execution data structures
+type Execution struct {
+	ExecID      string `json:"execId"`
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	Price       string `json:"price"`
+	Qty         string `json:"qty"`
+	Fee         string `json:"fee"`
+	FeeCurrency string `json:"feeCurrency"`
+	IsMaker     bool   `json:"isMaker"`
+	ExecTime    int64  `json:"execTime"`
 }
 
 type AssetInfoResponse struct {
@@ -50,17 +82,52 @@ type AssetInfoResponse struct {
 
 // Price data structures
 type TickerPriceResponse struct {
-	RetCode int    `json:"retCode"`
-	RetMsg  string `json:"retMsg"`
-	Result  struct {
-		Category string `json:"category"`
-		List     []struct {
-			Symbol    string `json:"symbol"`
-			LastPrice string `json:"lastPrice"`
-		} `json:"list"`
+	Time   int64 // envelope time (ms)
+	Result struct {
+		Category string         `json:"category"`
+		List     []tickerFields `json:"list"`
 	} `json:"result"`
 }
 
+// tickerFields holds the spot ticker fields shared by the REST tickers
+// endpoint and the tickers WebSocket topic
+type tickerFields struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	Bid1Price    string `json:"bid1Price"`
+	Bid1Size     string `json:"bid1Size"`
+	Ask1Price    string `json:"ask1Price"`
+	Ask1Size     string `json:"ask1Size"`
+	PrevPrice24h string `json:"prevPrice24h"`
+	Price24hPcnt string `json:"price24hPcnt"`
+	HighPrice24h string `json:"highPrice24h"`
+	LowPrice24h  string `json:"lowPrice24h"`
+	Volume24h    string `json:"volume24h"`
+	Turnover24h  string `json:"turnover24h"`
+}
+
+// applyTo copies every non-empty field onto t, so deltas that omit unchanged
+// fields keep the previous values
+func (f tickerFields) applyTo(t *exchange.Ticker) {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&t.Symbol, f.Symbol)
+	set(&t.LastPrice, f.LastPrice)
+	set(&t.Bid1Price, f.Bid1Price)
+	set(&t.Bid1Size, f.Bid1Size)
+	set(&t.Ask1Price, f.Ask1Price)
+	set(&t.Ask1Size, f.Ask1Size)
+	set(&t.PrevPrice24h, f.PrevPrice24h)
+	set(&t.Price24hPcnt, f.Price24hPcnt)
+	set(&t.HighPrice24h, f.HighPrice24h)
+	set(&t.LowPrice24h, f.LowPrice24h)
+	set(&t.Volume24h, f.Volume24h)
+	set(&t.Turnover24h, f.Turnover24h)
+}
+
 // HTTP client for requests
 var httpClient = &http.Client{Timeout: 4 * time.Second}
 
@@ -78,29 +145,24 @@ func firstNonEmpty(values ...interface{}) string {
 	return ""
 }
 
-// signV5 creates the Bybit v5 HMAC SHA256 signature
-func signV5(apiKey, secret string, query url.Values, timestamp string, recvWindow string) string {
-	// v5 signature payload: timestamp + apiKey + recvWindow + queryString (only the actual URL query)
-	// Build canonical query matching what we will send
-	var keys []string
-	for k := range query {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	var encoded string
-	for i, k := range keys {
-		if i > 0 {
-			encoded += "&"
-		}
-		// Values are simple; use first value
-		encoded += fmt.Sprintf("%s=%s", k, query.Get(k))
-	}
-	payload := timestamp + apiKey + recvWindow + encoded
+// signV5 creates the Bybit v5 HMAC SHA256 signature.
+// payload is the URL query string for GET requests and the raw JSON body for POST.
+func signV5(apiKey, secret string, timestamp string, recvWindow string, payload string) string {
+	// v5 signature payload: timestamp + apiKey + recvWindow + (queryString | jsonBody)
 	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(payload))
+	mac.Write([]byte(timestamp + apiKey + recvWindow + payload))
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+// parseMillis parses a millisecond timestamp string, returning 0 when empty or invalid
+func parseMillis(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
 // toNumString converts interface{} to a numeric-like string, defaulting nil/"<nil>"/"" to "0"
 func toNumString(v interface{}) string {
 	if v == nil {
@@ -129,55 +191,20 @@ func toNumString(v interface{}) string {
 
 // getSpotHoldings fetches wallet balances via REST v5
 func (s *BybitService) getSpotHoldings(ctx context.Context, userID string) ([]Holding, error) {
-	creds, err := s.GetBybitByUserId(userID)
-	if err != nil {
-		return nil, fmt.Errorf("bybit credentials not found: %w", err)
-	}
-
-	endpoint := "https://api.bybit.com/v5/account/wallet-balance"
-	accountType := "UNIFIED"
-	recvWindow := "8000"
-	timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
-
-	// Actual query string
-	q := url.Values{}
-	q.Set("accountType", accountType)
-
-	signature := signV5(creds.ApiKey, creds.ApiSecret, q, timestamp, recvWindow)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	client, err := s.newPrivateClient(userID)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("X-BAPI-API-KEY", creds.ApiKey)
-	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
-	req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
-	req.Header.Set("X-BAPI-SIGN", signature)
-	req.Header.Set("X-BAPI-SIGN-TYPE", "2")
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("bybit wallet-balance error: %s", string(body))
-	}
+	q := url.Values{}
+	q.Set("accountType", "UNIFIED")
 
-	var raw map[string]interface{}
-	if err := json.Unmarshal(body, &raw); err != nil {
+	var result map[string]interface{}
+	if err := client.get(ctx, "/v5/account/wallet-balance", q, &result); err != nil {
 		return nil, err
 	}
 
-	// Handle Bybit business error even with HTTP 200
-	if rc, ok := raw["retCode"].(float64); ok && rc != 0 {
-		retMsg := fmt.Sprintf("%v", raw["retMsg"])
-		return nil, fmt.Errorf("bybit error retCode=%v: %s", rc, retMsg)
-	}
-
 	var holdings []Holding
-	result, _ := raw["result"].(map[string]interface{})
 	if result != nil {
 		list, _ := result["list"].([]interface{})
 		for _, item := range list {
@@ -189,116 +216,86 @@ func (s *BybitService) getSpotHoldings(ctx context.Context, userID string) ([]Ho
 				if coin == "" {
 					continue
 				}
-				// Prefer total wallet balance if present
-				total := toNumString(cm["walletBalance"]) // unified total
-				if total == "0" || total == "" {
-					// Fallback to free+locked if provided
-					free := toNumString(cm["free"])     // may be nil
-					locked := toNumString(cm["locked"]) // may be nil
-					// store separately; UI will sum
-					holdings = append(holdings, Holding{Coin: coin, Free: free, Locked: locked})
-				} else {
-					// Put total into Free to represent overall amount; Locked set to 0
-					holdings = append(holdings, Holding{Coin: coin, Free: total, Locked: "0"})
-				}
+				holdings = append(holdings, holdingFromWallet(coin,
+					toNumString(cm["walletBalance"]), toNumString(cm["free"]), toNumString(cm["locked"])))
 			}
 		}
 	}
 	return holdings, nil
 }
 
-// getCurrentPrice gets current price for a symbol via REST API
-func getCurrentPrice(symbol string) (string, error) {
-	// Format symbol for Bybit API (e.g., "btc" -> "BTCUSDT")
-	symbol = fmt.Sprintf("%sUSDT", strings.ToUpper(symbol))
+// holdingFromWallet builds a holding from a wallet coin entry, shared by the
+// REST wallet balance and the private wallet stream
+func holdingFromWallet(coin, walletBalance, free, locked string) Holding {
+	// Prefer total wallet balance if present
+	if walletBalance == "0" || walletBalance == "" {
+		// Fallback to free+locked if provided; store separately, UI will sum
+		return Holding{Coin: coin, Free: free, Locked: locked}
+	}
+	// Put total into Free to represent overall amount; Locked set to 0
+	return Holding{Coin: coin, Free: walletBalance, Locked: "0"}
+}
+
+// getTicker gets the latest ticker for a symbol from the REST host rest
+func getTicker(ctx context.Context, rest, symbol string) (*exchange.Ticker, error) {
+	// Resolve coin or symbol against listed instruments (e.g., "btc" -> "BTCUSDT")
+	instruments := instrumentsFor(rest)
+	symbol, err := resolveSpotSymbol(ctx, instruments, symbol)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build request URL
-	baseURL := "https://api.bybit.com/v5/market/tickers"
+	baseURL := rest + "/v5/market/tickers"
 	params := url.Values{}
 	params.Set("category", "spot")
 	params.Set("symbol", symbol)
 
 	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	// Make request
-	resp, err := httpClient.Get(reqURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch price: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-
-	// Parse response
+	// Make request, retrying transient failures
 	var priceResp TickerPriceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&priceResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if priceResp.RetCode != 0 {
-		return "", fmt.Errorf("API error: %s", priceResp.RetMsg)
+	err = withRetry(ctx, func() error {
+		resp, err := publicGet(ctx, reqURL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch price: %w", err)
+		}
+		defer resp.Body.Close()
+		priceResp.Time, err = decodeEnvelopeTime(resp, "/v5/market/tickers", &priceResp.Result)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	if len(priceResp.Result.List) == 0 {
-		return "", fmt.Errorf("no price data found for symbol %s", symbol)
+		return nil, fmt.Errorf("no price data found for symbol %s", symbol)
 	}
 
-	return priceResp.Result.List[0].LastPrice, nil
+	ticker := &exchange.Ticker{Time: priceResp.Time}
+	priceResp.Result.List[0].applyTo(ticker)
+	if inst, ok := instruments.peek(ticker.Symbol); ok {
+		ticker.Base = inst.BaseCoin
+		ticker.Quote = inst.QuoteCoin
+	}
+	return ticker, nil
 }
 
 // getAssetBalance retrieves balance for a specific coin from Bybit
 func (s *BybitService) getAssetBalance(ctx context.Context, userID string, coin string) (*CoinBalance, error) {
-	creds, err := s.GetBybitByUserId(userID)
+	client, err := s.newPrivateClient(userID)
 	if err != nil {
-		return nil, fmt.Errorf("bybit credentials not found: %w", err)
+		return nil, err
 	}
 
-	endpoint := "https://api.bybit.com/v5/account/wallet-balance"
-	recvWindow := "8000"
-	timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
-
 	// Build query parameters
 	q := url.Values{}
 	q.Set("accountType", "UNIFIED")
 	q.Set("coin", strings.ToUpper(coin))
 
-	// Create signature and make request
-	signature := signV5(creds.ApiKey, creds.ApiSecret, q, timestamp, recvWindow)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("X-BAPI-API-KEY", creds.ApiKey)
-	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
-	req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
-	req.Header.Set("X-BAPI-SIGN", signature)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("bybit asset balance error: %s", string(body))
-	}
-
 	var assetResp AssetInfoResponse
-	if err := json.Unmarshal(body, &assetResp); err != nil {
-		return nil, fmt.Errorf("failed to parse asset response: %w", err)
-	}
-
-	if assetResp.RetCode != 0 {
-		return nil, fmt.Errorf("bybit error retCode=%d: %s", assetResp.RetCode, assetResp.RetMsg)
+	if err := client.get(ctx, "/v5/account/wallet-balance", q, &assetResp.Result); err != nil {
+		return nil, err
 	}
 
 	// Find the coin in the response
@@ -1,263 +1,725 @@
 package bybit
 
 import (
+	"coin-control/backend/exchange"
 	"context"
-	"fmt"
+	"encoding/json"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-type PriceData struct {
-	Symbol string `json:"symbol"`
-	Price  string `json:"price"`
-	Time   int64  `json:"time"`
-}
+type PriceData = exchange.PriceData
 
 type WebSocketManager struct {
-	conn          *websocket.Conn
-	subscribers   map[string][]chan PriceData
-	mu            sync.RWMutex
-	isConnected   bool
-	reconnectChan chan bool
-	ctx           context.Context
-	cancel        context.CancelFunc
+	ep               endpoints
+	instruments      *instrumentCache
+	session          *wsSession // current connection, nil while disconnected
+	prices           *exchange.PriceHub
+	tickers          map[string]*exchange.Ticker
+	books            map[string]*localBook // keyed by orderbook topic
+	bookSubscribers  map[string][]chan OrderBook
+	tradeSubscribers map[string][]*tradeSubscriber
+	klineSubscribers map[string][]chan Candle  // keyed by kline topic
+	resolved         map[chan PriceData]string // Subscribe channel -> symbol it was resolved to
+	topics           map[string]bool           // desired topic -> confirmed by Bybit
+	pendingReqs      map[string][]string       // req_id -> topics awaiting confirmation
+	reqSeq           uint64
+	health           *connHealth
+	mu               sync.RWMutex
+	isConnected      bool
+	reconnectChan    chan bool
+	ctx              context.Context
+	cancel           context.CancelFunc
+}
+
+// wsMessage is the envelope of every public stream message
+type wsMessage struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Ts    int64           `json:"ts"`
+	Data  json.RawMessage `json:"data"`
+
+	// Set on responses to subscribe, unsubscribe and ping requests
+	Op      string `json:"op"`
+	Success *bool  `json:"success"`
+	RetMsg  string `json:"ret_msg"`
+	ReqID   string `json:"req_id"`
 }
 
 type TickerResponse struct {
-	Topic string `json:"topic"`
-	Type  string `json:"type"`
-	Data  struct {
-		Symbol    string `json:"symbol"`
-		LastPrice string `json:"lastPrice"`
-		Ts        int64  `json:"ts"`
-	} `json:"data"`
-}
-
-var wsManager *WebSocketManager
-var wsOnce sync.Once
-
-func GetWebSocketManager() *WebSocketManager {
-	wsOnce.Do(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		wsManager = &WebSocketManager{
-			subscribers:   make(map[string][]chan PriceData),
-			reconnectChan: make(chan bool, 1),
-			ctx:           ctx,
-			cancel:        cancel,
-		}
-		go wsManager.connectionManager()
-	})
-	return wsManager
+	Topic string       `json:"topic"`
+	Type  string       `json:"type"`
+	Ts    int64        `json:"ts"`
+	Data  tickerFields `json:"data"`
 }
 
+var (
+	wsManagers   = make(map[string]*WebSocketManager) // keyed by public stream host
+	wsManagersMu sync.Mutex
+)
+
+// webSocketManagers returns the public streams of every environment in use
+func webSocketManagers() []*WebSocketManager {
+	wsManagersMu.Lock()
+	defer wsManagersMu.Unlock()
+	out := make([]*WebSocketManager, 0, len(wsManagers))
+	for _, ws := range wsManagers {
+		out = append(out, ws)
+	}
+	return out
+}
+
+// webSocketManagerFor returns the public stream serving ep, starting it on first use
+func webSocketManagerFor(ep endpoints) *WebSocketManager {
+	wsManagersMu.Lock()
+	defer wsManagersMu.Unlock()
+
+	if ws, ok := wsManagers[ep.publicWS]; ok {
+		return ws
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ws := &WebSocketManager{
+		ep:               ep,
+		instruments:      instrumentsFor(ep.rest),
+		prices:           exchange.NewPriceHub(),
+		tickers:          make(map[string]*exchange.Ticker),
+		books:            make(map[string]*localBook),
+		bookSubscribers:  make(map[string][]chan OrderBook),
+		tradeSubscribers: make(map[string][]*tradeSubscriber),
+		klineSubscribers: make(map[string][]chan Candle),
+		resolved:         make(map[chan PriceData]string),
+		topics:           make(map[string]bool),
+		pendingReqs:      make(map[string][]string),
+		health:           newConnHealth(),
+		reconnectChan:    make(chan bool, 1),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+	wsManagers[ep.publicWS] = ws
+	go ws.connectionManager()
+	go ws.watchdog()
+	return ws
+}
+
+// connectionManager keeps the public stream connected, backing off
+// exponentially while connection attempts fail
 func (ws *WebSocketManager) connectionManager() {
 	for {
-		select {
-		case <-ws.ctx.Done():
+		if ws.ctx.Err() != nil {
 			return
-		default:
-			if err := ws.connect(); err != nil {
-				log.Printf("WebSocket connection failed: %v", err)
-				time.Sleep(5 * time.Second)
-				continue
+		}
+		ws.beginAttempt()
+		session, err := ws.connect()
+		if err != nil {
+			ws.connectFailed(err)
+			if !ws.sleep(reconnectDelay(ws.health.attempts())) {
+				return
 			}
-			ws.handleMessages()
+			continue
 		}
+		ws.connected()
+		ws.disconnected(ws.handleMessages(session))
+		// Short jittered pause so a flapping endpoint is not hammered
+		if !ws.sleep(reconnectDelay(1)) {
+			return
+		}
+	}
+}
+
+// sleep waits for d and reports false when the manager was closed meanwhile
+func (ws *WebSocketManager) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ws.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
 
-func (ws *WebSocketManager) connect() error {
+func (ws *WebSocketManager) connect() (*wsSession, error) {
 	log.Printf("Attempting to connect to Bybit WebSocket...")
-	conn, _, err := websocket.DefaultDialer.Dial("wss://stream.bybit.com/v5/public/spot", nil)
+	conn, _, err := websocket.DefaultDialer.Dial(ws.ep.publicWS+"/v5/public/spot", nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	session := newSession(conn)
 
 	ws.mu.Lock()
-	ws.conn = conn
+	ws.session = session
 	ws.isConnected = true
+	// A new connection starts without subscriptions
+	ws.resubscribeAll()
 	ws.mu.Unlock()
 
-	// Start ping routine to keep connection alive
-	go ws.pingRoutine()
-
 	log.Printf("WebSocket connected successfully")
-	return nil
-}
-
-func (ws *WebSocketManager) pingRoutine() {
-	ticker := time.NewTicker(20 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ws.ctx.Done():
-			return
-		case <-ticker.C:
-			ws.mu.Lock()
-			if ws.conn != nil && ws.isConnected {
-				if err := ws.conn.WriteJSON(map[string]string{"op": "ping"}); err != nil {
-					log.Printf("Failed to send ping: %v", err)
-					ws.mu.Unlock()
-					return
-				}
-			}
-			ws.mu.Unlock()
-		}
-	}
+	return session, nil
 }
 
-func (ws *WebSocketManager) handleMessages() {
+// handleMessages reads until the connection fails and returns the read error
+func (ws *WebSocketManager) handleMessages(session *wsSession) error {
 	defer func() {
+		session.close()
 		ws.mu.Lock()
-		if ws.conn != nil {
-			ws.conn.Close()
+		if ws.session == session {
+			ws.session = nil
+			ws.isConnected = false
 		}
-		ws.isConnected = false
 		ws.mu.Unlock()
 	}()
 
 	for {
-		select {
-		case <-ws.ctx.Done():
-			return
-		default:
-			var response TickerResponse
-			if err := ws.conn.ReadJSON(&response); err != nil {
-				log.Printf("WebSocket read error: %v", err)
-				return
+		var msg wsMessage
+		if err := session.conn.ReadJSON(&msg); err != nil {
+			if ws.ctx.Err() != nil {
+				return nil
 			}
+			return err
+		}
+		if msg.Op != "" {
+			ws.handleOpResponse(msg)
+			continue
+		}
+		if msg.Topic != "" {
+			ws.health.touch(msg.Topic)
+		}
+		ws.dispatch(msg)
+	}
+}
 
-			if response.Type == "snapshot" || response.Type == "delta" {
-				priceData := PriceData{
-					Symbol: response.Data.Symbol,
-					Price:  response.Data.LastPrice,
-					Time:   response.Data.Ts,
-				}
-				ws.broadcast(priceData)
-			}
+// dispatch routes a stream message to the handler of its topic
+func (ws *WebSocketManager) dispatch(msg wsMessage) {
+	if msg.Type != "snapshot" && msg.Type != "delta" {
+		return
+	}
+	switch {
+	case strings.HasPrefix(msg.Topic, "tickers."):
+		response := TickerResponse{Topic: msg.Topic, Type: msg.Type, Ts: msg.Ts}
+		if err := json.Unmarshal(msg.Data, &response.Data); err != nil {
+			dbg("bad ticker message: %v", err)
+			return
+		}
+		ticker := ws.mergeTicker(response)
+		ws.broadcast(PriceData{
+			Symbol: ticker.Symbol,
+			Base:   ticker.Base,
+			Quote:  ticker.Quote,
+			Price:  ticker.LastPrice,
+			Time:   ticker.Time,
+			Ticker: ticker,
+		})
+	case strings.HasPrefix(msg.Topic, "orderbook."):
+		var data bookData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			dbg("bad orderbook message: %v", err)
+			return
+		}
+		ws.handleOrderBook(msg.Topic, msg.Type, msg.Ts, data)
+	case strings.HasPrefix(msg.Topic, "publicTrade."):
+		var data []tradeRecord
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			dbg("bad publicTrade message: %v", err)
+			return
+		}
+		ws.handleTrades(strings.TrimPrefix(msg.Topic, "publicTrade."), data)
+	case strings.HasPrefix(msg.Topic, "kline."):
+		var data []klineRecord
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			dbg("bad kline message: %v", err)
+			return
 		}
+		ws.handleKlines(msg.Topic, data)
 	}
 }
 
-func (ws *WebSocketManager) broadcast(data PriceData) {
-	ws.mu.RLock()
-	subscribers, exists := ws.subscribers[data.Symbol]
-	ws.mu.RUnlock()
+// mergeTicker applies a snapshot or delta to the stored ticker of its symbol
+// and returns a copy of the merged state
+func (ws *WebSocketManager) mergeTicker(response TickerResponse) exchange.Ticker {
+	symbol := response.Data.Symbol
 
-	if exists {
-		for _, ch := range subscribers {
-			select {
-			case ch <- data:
-			default:
-				// Channel is full, skip
-			}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ticker, ok := ws.tickers[symbol]
+	if !ok || response.Type == "snapshot" {
+		ticker = &exchange.Ticker{Symbol: symbol}
+		if inst, found := ws.instruments.peek(symbol); found {
+			ticker.Base = inst.BaseCoin
+			ticker.Quote = inst.QuoteCoin
 		}
+		ws.tickers[symbol] = ticker
 	}
+	response.Data.applyTo(ticker)
+	if response.Ts > 0 {
+		ticker.Time = response.Ts
+	}
+	return *ticker
 }
 
+// broadcast hands a price update to the hub, which applies each subscriber's policy
+func (ws *WebSocketManager) broadcast(data PriceData) {
+	ws.prices.Publish(data)
+}
+
+// Subscribe streams prices for a coin or symbol. Kept for callers that pass a
+// bare coin; the coin is paired with its preferred quote (usually USDT).
 func (ws *WebSocketManager) Subscribe(symbol string) (chan PriceData, error) {
-	symbol = strings.ToUpper(symbol) + "USDT"
+	resolved, err := resolveSpotSymbol(ws.ctx, ws.instruments, symbol)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := ws.SubscribeSymbol(resolved, exchange.SubscribeOptions{})
+	if err != nil {
+		return nil, err
+	}
 
-	ch := make(chan PriceData, 10)
+	ws.mu.Lock()
+	ws.resolved[ch] = resolved
+	ws.mu.Unlock()
+	return ch, nil
+}
+
+// SubscribeSymbol streams prices for an exact spot symbol such as "ETHBTC".
+// opts selects how updates are delivered when the receiver falls behind.
+func (ws *WebSocketManager) SubscribeSymbol(symbol string, opts exchange.SubscribeOptions) (chan PriceData, error) {
+	inst, err := ws.instruments.lookup(ws.ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	symbol = inst.Symbol
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
+	ch, _ := ws.prices.Add(symbol, opts)
+	// Subscribed now when connected, otherwise replayed once connected
+	ws.wantTopic(tickerTopic(symbol))
+
+	return ch, nil
+}
+
+// Unsubscribe is the counterpart of Subscribe. It unsubscribes ch from the
+// symbol it was resolved to, so instrument changes cannot leak the channel.
+func (ws *WebSocketManager) Unsubscribe(symbol string, ch chan PriceData) {
 	ws.mu.Lock()
-	ws.subscribers[symbol] = append(ws.subscribers[symbol], ch)
-	isConnected := ws.isConnected
+	resolved, ok := ws.resolved[ch]
+	delete(ws.resolved, ch)
 	ws.mu.Unlock()
 
-	// Wait for connection if not connected
-	if !isConnected {
-		log.Printf("WebSocket not connected, waiting...")
-		time.Sleep(2 * time.Second) // Give it some time to connect
+	if !ok {
+		log.Printf("Unsubscribe of %s: channel was not subscribed", symbol)
+		return
+	}
+	ws.UnsubscribeSymbol(resolved, ch)
+}
 
-		ws.mu.RLock()
-		isConnected = ws.isConnected
-		ws.mu.RUnlock()
+// UnsubscribeSymbol stops delivering updates for an exact spot symbol to ch
+func (ws *WebSocketManager) UnsubscribeSymbol(symbol string, ch chan PriceData) {
+	symbol = strings.ToUpper(symbol)
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.prices.Remove(symbol, ch) == 0 {
+		delete(ws.tickers, symbol)
+		ws.dropTopic(tickerTopic(symbol))
 	}
+}
 
-	if isConnected {
-		if err := ws.subscribeToSymbol(symbol); err != nil {
-			log.Printf("Failed to subscribe to symbol %s: %v", symbol, err)
-			return nil, err
-		}
-		log.Printf("Successfully subscribed to %s", symbol)
-	} else {
-		log.Printf("WebSocket still not connected for symbol %s", symbol)
+// PriceStats returns delivery counters of every price subscriber
+func (ws *WebSocketManager) PriceStats() []exchange.SubscriberStats {
+	return ws.prices.Stats()
+}
+
+// tickerTopic returns the public ticker topic of a symbol
+func tickerTopic(symbol string) string {
+	return "tickers." + symbol
+}
+
+// =============================================================================
+// Order book streams
+// =============================================================================
+
+// SubscribeOrderBook streams the local L2 book of a spot symbol at the given
+// depth. Each send carries the full book after an applied update; a slow
+// receiver only ever sees the latest book.
+func (ws *WebSocketManager) SubscribeOrderBook(symbol string, depth int) (chan OrderBook, error) {
+	if err := validateBookDepth(depth); err != nil {
+		return nil, err
+	}
+	inst, err := ws.instruments.lookup(ws.ctx, symbol)
+	if err != nil {
+		return nil, err
 	}
+	topic := orderBookTopic(inst.Symbol, depth)
+
+	ch := make(chan OrderBook, 1)
 
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if len(ws.bookSubscribers[topic]) == 0 {
+		ws.books[topic] = newLocalBook(inst, depth)
+	}
+	ws.bookSubscribers[topic] = append(ws.bookSubscribers[topic], ch)
+	ws.wantTopic(topic)
 	return ch, nil
 }
 
-func (ws *WebSocketManager) Unsubscribe(symbol string, ch chan PriceData) {
-	symbol = strings.ToUpper(symbol) + "USDT"
+// UnsubscribeOrderBook stops delivering books to ch
+func (ws *WebSocketManager) UnsubscribeOrderBook(symbol string, depth int, ch chan OrderBook) {
+	topic := orderBookTopic(strings.ToUpper(symbol), depth)
 
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
-	subscribers := ws.subscribers[symbol]
+	subscribers := ws.bookSubscribers[topic]
 	for i, subscriber := range subscribers {
 		if subscriber == ch {
 			close(ch)
-			ws.subscribers[symbol] = append(subscribers[:i], subscribers[i+1:]...)
+			ws.bookSubscribers[topic] = append(subscribers[:i], subscribers[i+1:]...)
 			break
 		}
 	}
 
-	if len(ws.subscribers[symbol]) == 0 {
-		delete(ws.subscribers, symbol)
-		if ws.isConnected {
-			ws.unsubscribeFromSymbol(symbol)
+	if len(ws.bookSubscribers[topic]) == 0 {
+		delete(ws.bookSubscribers, topic)
+		delete(ws.books, topic)
+		ws.dropTopic(topic)
+	}
+}
+
+// OrderBookSnapshot returns the live book for a subscribed symbol and depth
+func (ws *WebSocketManager) OrderBookSnapshot(symbol string, depth int) (*OrderBook, bool) {
+	topic := orderBookTopic(strings.ToUpper(symbol), depth)
+
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	book, ok := ws.books[topic]
+	if !ok || !book.synced {
+		return nil, false
+	}
+	snap := book.snapshot()
+	return &snap, true
+}
+
+// handleOrderBook applies a snapshot or delta and fans the book out. On a
+// sequence gap the topic is resubscribed to obtain a fresh snapshot.
+func (ws *WebSocketManager) handleOrderBook(topic, msgType string, ts int64, data bookData) {
+	ws.mu.Lock()
+	book, ok := ws.books[topic]
+	if !ok {
+		ws.mu.Unlock()
+		return
+	}
+	// u == 1 means Bybit restarted the book; treat it as a snapshot
+	if msgType == "snapshot" || data.UpdateID == 1 {
+		book.applySnapshot(data, ts)
+	} else if !book.synced {
+		// Waiting for the snapshot of a pending resync
+		ws.mu.Unlock()
+		return
+	} else if err := book.applyDelta(data, ts); err != nil {
+		ws.mu.Unlock()
+		log.Printf("Order book out of sync, resubscribing: %v", err)
+		go ws.resyncTopic(topic)
+		return
+	}
+	snap := book.snapshot()
+	ws.mu.Unlock()
+
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	for _, ch := range ws.bookSubscribers[topic] {
+		sendLatest(ch, snap)
+	}
+}
+
+// resyncTopic resubscribes a topic so Bybit sends a fresh snapshot
+func (ws *WebSocketManager) resyncTopic(topic string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if _, wanted := ws.topics[topic]; !wanted || !ws.isConnected {
+		// Dropped meanwhile, or the reconnect replays it anyway
+		return
+	}
+	ws.topics[topic] = false
+	ws.requestTopics("unsubscribe", []string{topic})
+	ws.requestTopics("subscribe", []string{topic})
+}
+
+// =============================================================================
+// Public trade streams
+// =============================================================================
+
+// tradeBuffer is the channel capacity of a trade subscriber. Trades are never
+// coalesced; a subscriber that falls further behind loses them.
+const tradeBuffer = 100
+
+// tradeSubscriber is a single trade channel and its delivery counters
+type tradeSubscriber struct {
+	ch        chan Trade
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// SubscribeTrades streams public trades of a spot symbol. The topic is
+// subscribed for the first subscriber and released with the last one.
+// Trades that do not fit the subscriber's buffer are dropped and counted,
+// see TradeStats.
+func (ws *WebSocketManager) SubscribeTrades(symbol string) (chan Trade, error) {
+	inst, err := ws.instruments.lookup(ws.ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	symbol = inst.Symbol
+
+	sub := &tradeSubscriber{ch: make(chan Trade, tradeBuffer)}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.tradeSubscribers[symbol] = append(ws.tradeSubscribers[symbol], sub)
+	ws.wantTopic(tradeTopic(symbol))
+	return sub.ch, nil
+}
+
+// UnsubscribeTrades stops delivering trades of a symbol to ch
+func (ws *WebSocketManager) UnsubscribeTrades(symbol string, ch chan Trade) {
+	symbol = strings.ToUpper(symbol)
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	subscribers := ws.tradeSubscribers[symbol]
+	for i, subscriber := range subscribers {
+		if subscriber.ch == ch {
+			close(ch)
+			ws.tradeSubscribers[symbol] = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+
+	if len(ws.tradeSubscribers[symbol]) == 0 {
+		delete(ws.tradeSubscribers, symbol)
+		ws.dropTopic(tradeTopic(symbol))
+	}
+}
+
+// handleTrades fans trades out to subscribers, dropping and counting them for
+// full channels
+func (ws *WebSocketManager) handleTrades(symbol string, records []tradeRecord) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	subscribers := ws.tradeSubscribers[symbol]
+	if len(subscribers) == 0 {
+		return
+	}
+	for _, record := range records {
+		trade := record.toTrade()
+		for _, sub := range subscribers {
+			select {
+			case sub.ch <- trade:
+				sub.delivered.Add(1)
+			default:
+				if sub.dropped.Add(1) == 1 {
+					log.Printf("Trade subscriber of %s is falling behind, dropping trades", symbol)
+				}
+			}
+		}
+	}
+}
+
+// TradeStats returns delivery counters of every trade subscriber, ordered by symbol
+func (ws *WebSocketManager) TradeStats() []exchange.SubscriberStats {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	var out []exchange.SubscriberStats
+	for symbol, subscribers := range ws.tradeSubscribers {
+		for _, sub := range subscribers {
+			out = append(out, exchange.SubscriberStats{
+				Symbol:    symbol,
+				Policy:    exchange.PolicyBounded,
+				Delivered: sub.delivered.Load(),
+				Dropped:   sub.dropped.Load(),
+			})
 		}
 	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
 }
 
-func (ws *WebSocketManager) subscribeToSymbol(symbol string) error {
-	if ws.conn == nil {
-		return fmt.Errorf("connection not established")
+// =============================================================================
+// Kline streams
+// =============================================================================
+
+// SubscribeKlines streams candles of a spot symbol. Every update of the
+// current candle is delivered; the last one of an interval has Confirmed set.
+func (ws *WebSocketManager) SubscribeKlines(symbol string, interval string) (chan Candle, error) {
+	code, err := normalizeInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := ws.instruments.lookup(ws.ctx, symbol)
+	if err != nil {
+		return nil, err
 	}
+	topic := klineTopic(inst.Symbol, code)
+
+	ch := make(chan Candle, 10)
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	subscribeMsg := map[string]interface{}{
-		"op":   "subscribe",
-		"args": []string{"tickers." + symbol},
+	ws.klineSubscribers[topic] = append(ws.klineSubscribers[topic], ch)
+	ws.wantTopic(topic)
+	return ch, nil
+}
+
+// UnsubscribeKlines stops delivering candles of a symbol and interval to ch
+func (ws *WebSocketManager) UnsubscribeKlines(symbol string, interval string, ch chan Candle) {
+	code, err := normalizeInterval(interval)
+	if err != nil {
+		return
 	}
+	topic := klineTopic(strings.ToUpper(symbol), code)
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	return ws.conn.WriteJSON(subscribeMsg)
+	subscribers := ws.klineSubscribers[topic]
+	for i, subscriber := range subscribers {
+		if subscriber == ch {
+			close(ch)
+			ws.klineSubscribers[topic] = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+
+	if len(ws.klineSubscribers[topic]) == 0 {
+		delete(ws.klineSubscribers, topic)
+		ws.dropTopic(topic)
+	}
 }
 
-func (ws *WebSocketManager) unsubscribeFromSymbol(symbol string) error {
-	if ws.conn == nil {
-		return fmt.Errorf("connection not established")
+// handleKlines fans candles out to subscribers. Confirmed candles are never
+// dropped in favour of in-progress ones: when a channel is full the stale
+// in-progress update is discarded first.
+func (ws *WebSocketManager) handleKlines(topic string, records []klineRecord) {
+	symbol := topic[strings.LastIndexByte(topic, '.')+1:]
+
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	for _, record := range records {
+		candle := record.toCandle(symbol)
+		for _, ch := range ws.klineSubscribers[topic] {
+			if candle.Confirmed {
+				sendConfirmedCandle(ch, candle)
+				continue
+			}
+			select {
+			case ch <- candle:
+			default:
+				// Channel is full, skip
+			}
+		}
+	}
+}
+
+// sendConfirmedCandle delivers a confirmed candle without blocking. When ch
+// is full, buffered in-progress candles are discarded to make room; buffered
+// confirmed ones are put back in order.
+func sendConfirmedCandle(ch chan Candle, candle Candle) {
+	select {
+	case ch <- candle:
+		return
+	default:
 	}
 
-	unsubscribeMsg := map[string]interface{}{
-		"op":   "unsubscribe",
-		"args": []string{"tickers." + symbol},
+	var kept []Candle
+	for drained := false; !drained; {
+		select {
+		case old := <-ch:
+			if old.Confirmed {
+				kept = append(kept, old)
+			}
+		default:
+			drained = true
+		}
+	}
+	for _, c := range append(kept, candle) {
+		select {
+		case ch <- c:
+		default:
+			log.Printf("Kline subscriber of %s is full, dropped confirmed candle %d", c.Symbol, c.Start)
+		}
 	}
+}
 
-	return ws.conn.WriteJSON(unsubscribeMsg)
+// sendLatest delivers v without blocking, replacing an unread older value
+func sendLatest[T any](ch chan T, v T) {
+	select {
+	case ch <- v:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- v:
+	default:
+	}
 }
 
 func (ws *WebSocketManager) Close() {
 	if ws.cancel != nil {
 		ws.cancel()
 	}
+	ws.setState(StateClosed, "")
 
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
-	if ws.conn != nil {
-		ws.conn.Close()
+	if ws.session != nil {
+		ws.session.close()
+	}
+
+	ws.prices.CloseAll()
+
+	for _, subscribers := range ws.bookSubscribers {
+		for _, ch := range subscribers {
+			close(ch)
+		}
+	}
+	ws.bookSubscribers = make(map[string][]chan OrderBook)
+	ws.books = make(map[string]*localBook)
+
+	for _, subscribers := range ws.tradeSubscribers {
+		for _, sub := range subscribers {
+			close(sub.ch)
+		}
 	}
+	ws.tradeSubscribers = make(map[string][]*tradeSubscriber)
 
-	for _, subscribers := range ws.subscribers {
+	for _, subscribers := range ws.klineSubscribers {
 		for _, ch := range subscribers {
 			close(ch)
 		}
 	}
-	ws.subscribers = make(map[string][]chan PriceData)
+	ws.klineSubscribers = make(map[string][]chan Candle)
+	ws.topics = make(map[string]bool)
+	ws.pendingReqs = make(map[string][]string)
 }
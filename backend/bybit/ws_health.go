@@ -0,0 +1,281 @@
+package bybit
+
+import (
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ConnState is the state of the public WebSocket connection
+type ConnState string
+
+const (
+	StateConnecting   ConnState = "connecting"
+	StateConnected    ConnState = "connected"
+	StateDegraded     ConnState = "degraded" // connected, but pongs are late or topics are silent
+	StateReconnecting ConnState = "reconnecting"
+	StateClosed       ConnState = "closed"
+)
+
+const (
+	wsStatusEvent = "ws-status"
+
+	// watchdogInterval is how often connection health is evaluated
+	watchdogInterval = 5 * time.Second
+	// pongLateAfter marks the connection degraded when no pong arrived in time
+	pongLateAfter = 30 * time.Second
+	// pongTimeout forces a reconnect after this long without a pong
+	pongTimeout = 60 * time.Second
+	// staleTopicAfter marks a confirmed topic stale when it has been silent
+	// this long. Quiet markets are legitimately silent, so stale topics only
+	// mark the connection degraded; a reconnect needs missing pongs.
+	staleTopicAfter = 30 * time.Second
+)
+
+// WSStatus describes the public WebSocket connection for the ws-status event
+type WSStatus struct {
+	Host        string        `json:"host"` // public stream base, e.g. wss://stream.bybit.com
+	State       ConnState     `json:"state"`
+	Since       int64         `json:"since"`   // ms, when State was entered
+	Attempt     int           `json:"attempt"` // reconnect attempts since the last successful connect
+	LastError   string        `json:"lastError,omitempty"`
+	LastPong    int64         `json:"lastPong,omitempty"`
+	StaleTopics []string      `json:"staleTopics,omitempty"`
+	Topics      []TopicStatus `json:"topics"`
+}
+
+// connHealth holds the health data of the public connection. It has its own
+// lock so the read loop can record messages without contending with ws.mu.
+type connHealth struct {
+	mu          sync.Mutex
+	state       ConnState
+	since       time.Time
+	attempt     int
+	lastError   string
+	lastPong    time.Time
+	lastMessage map[string]time.Time // topic -> last data or confirmation
+	stale       []string
+}
+
+func newConnHealth() *connHealth {
+	return &connHealth{
+		state:       StateConnecting,
+		since:       time.Now(),
+		lastMessage: make(map[string]time.Time),
+	}
+}
+
+// touch records activity on a topic
+func (h *connHealth) touch(topic string) {
+	h.mu.Lock()
+	h.lastMessage[topic] = time.Now()
+	h.mu.Unlock()
+}
+
+// pong records a ping response
+func (h *connHealth) pong() {
+	h.mu.Lock()
+	h.lastPong = time.Now()
+	h.mu.Unlock()
+}
+
+// attempts returns the connection attempts since the last successful connect
+func (h *connHealth) attempts() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.attempt
+}
+
+// forget drops the activity of a topic that is no longer wanted
+func (h *connHealth) forget(topic string) {
+	h.mu.Lock()
+	delete(h.lastMessage, topic)
+	h.mu.Unlock()
+}
+
+// beginAttempt moves to connecting (first connection) or reconnecting
+func (ws *WebSocketManager) beginAttempt() {
+	h := ws.health
+	h.mu.Lock()
+	h.attempt++
+	state := StateReconnecting
+	if h.state == StateConnecting {
+		state = StateConnecting
+	}
+	h.mu.Unlock()
+	ws.setState(state, "")
+}
+
+// connectFailed records a failed connection attempt
+func (ws *WebSocketManager) connectFailed(err error) {
+	log.Printf("WebSocket connection failed: %v", err)
+	ws.setState(StateReconnecting, err.Error())
+}
+
+// connected resets the attempt counter after a successful dial
+func (ws *WebSocketManager) connected() {
+	h := ws.health
+	h.mu.Lock()
+	h.attempt = 0
+	h.lastPong = time.Now()
+	h.stale = nil
+	h.mu.Unlock()
+	ws.setState(StateConnected, "")
+}
+
+// disconnected records a dropped connection
+func (ws *WebSocketManager) disconnected(err error) {
+	if ws.ctx.Err() != nil {
+		ws.setState(StateClosed, "")
+		return
+	}
+	msg := ""
+	if err != nil {
+		log.Printf("WebSocket read error: %v", err)
+		msg = err.Error()
+	}
+	ws.setState(StateReconnecting, msg)
+}
+
+// setState changes the connection state and emits ws-status when it changed
+// or an error is reported. It reports whether an event was emitted.
+func (ws *WebSocketManager) setState(state ConnState, errMsg string) bool {
+	h := ws.health
+	h.mu.Lock()
+	changed := h.state != state || errMsg != ""
+	if h.state != state {
+		h.state = state
+		h.since = time.Now()
+	}
+	if errMsg != "" {
+		h.lastError = errMsg
+	} else if state == StateConnected {
+		h.lastError = ""
+	}
+	h.mu.Unlock()
+
+	if changed {
+		ws.emitStatus()
+	}
+	return changed
+}
+
+// Status returns the current connection health
+func (ws *WebSocketManager) Status() WSStatus {
+	topics := ws.TopicStatuses()
+
+	h := ws.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := WSStatus{
+		Host:        ws.ep.publicWS,
+		State:       h.state,
+		Since:       h.since.UnixMilli(),
+		Attempt:     h.attempt,
+		LastError:   h.lastError,
+		StaleTopics: append([]string(nil), h.stale...),
+		Topics:      topics,
+	}
+	if !h.lastPong.IsZero() {
+		status.LastPong = h.lastPong.UnixMilli()
+	}
+	for i := range status.Topics {
+		if t, ok := h.lastMessage[status.Topics[i].Topic]; ok {
+			status.Topics[i].LastMessage = t.UnixMilli()
+		}
+	}
+	for _, topic := range h.stale {
+		for i := range status.Topics {
+			if status.Topics[i].Topic == topic {
+				status.Topics[i].Stale = true
+			}
+		}
+	}
+	return status
+}
+
+func (ws *WebSocketManager) emitStatus() {
+	ctx := getRuntimeCtx()
+	if ctx == nil {
+		return
+	}
+	runtime.EventsEmit(ctx, wsStatusEvent, ws.Status())
+}
+
+// watchdog evaluates pong and topic freshness, marking the connection degraded
+// and forcing a reconnect when pongs stop
+func (ws *WebSocketManager) watchdog() {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-ticker.C:
+			ws.checkHealth()
+		}
+	}
+}
+
+func (ws *WebSocketManager) checkHealth() {
+	ws.mu.RLock()
+	confirmed := make([]string, 0, len(ws.topics))
+	for topic, ok := range ws.topics {
+		if ok {
+			confirmed = append(confirmed, topic)
+		}
+	}
+	ws.mu.RUnlock()
+	sort.Strings(confirmed)
+
+	now := time.Now()
+	h := ws.health
+	h.mu.Lock()
+	if h.state != StateConnected && h.state != StateDegraded {
+		h.mu.Unlock()
+		return
+	}
+
+	sincePong := now.Sub(h.lastPong)
+	var stale []string
+	for _, topic := range confirmed {
+		if now.Sub(h.lastMessage[topic]) > staleTopicAfter {
+			stale = append(stale, topic)
+		}
+	}
+	staleChanged := !slices.Equal(stale, h.stale)
+	h.stale = stale
+	h.mu.Unlock()
+
+	switch {
+	case sincePong > pongTimeout:
+		ws.forceReconnect("no pong for " + sincePong.Round(time.Second).String())
+	case sincePong > pongLateAfter || len(stale) > 0:
+		if !ws.setState(StateDegraded, "") && staleChanged {
+			ws.emitStatus()
+		}
+	default:
+		if !ws.setState(StateConnected, "") && staleChanged {
+			ws.emitStatus()
+		}
+	}
+}
+
+// forceReconnect closes the connection; the read loop then reconnects
+func (ws *WebSocketManager) forceReconnect(reason string) {
+	log.Printf("WebSocket looks frozen (%s), reconnecting", reason)
+	ws.setState(StateReconnecting, reason)
+
+	ws.mu.RLock()
+	session := ws.session
+	ws.mu.RUnlock()
+	if session != nil {
+		session.close()
+	}
+}
@@ -0,0 +1,112 @@
+package bybit
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// pingInterval is how often a ping is sent on a connection
+	pingInterval = 20 * time.Second
+	// writeTimeout bounds a single WebSocket write
+	writeTimeout = 10 * time.Second
+	// writeQueueSize is the number of outgoing messages buffered per connection
+	writeQueueSize = 256
+
+	// Reconnect policy of the public stream
+	reconnectBaseDelay = 500 * time.Millisecond
+	reconnectMaxDelay  = 60 * time.Second
+)
+
+var errSessionClosed = errors.New("websocket session closed")
+
+// wsSession is one WebSocket connection. gorilla/websocket allows a single
+// concurrent writer, so every write goes through the session's command channel
+// and is performed by writeLoop. The writer and the ping loop live exactly as
+// long as the connection.
+type wsSession struct {
+	conn   *websocket.Conn
+	writes chan interface{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// newSession starts the writer and ping loop of a freshly dialed connection
+func newSession(conn *websocket.Conn) *wsSession {
+	s := &wsSession{
+		conn:   conn,
+		writes: make(chan interface{}, writeQueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.writeLoop()
+	go s.pingLoop()
+	return s
+}
+
+// send queues a JSON message without blocking. It fails when the session is
+// closed or its queue is full; either way the connection is being replaced and
+// desired topics are replayed on the next one.
+func (s *wsSession) send(msg interface{}) error {
+	select {
+	case <-s.done:
+		return errSessionClosed
+	default:
+	}
+	select {
+	case s.writes <- msg:
+		return nil
+	default:
+		s.close()
+		return errors.New("websocket write queue is full")
+	}
+}
+
+func (s *wsSession) writeLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.writes:
+			s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := s.conn.WriteJSON(msg); err != nil {
+				log.Printf("WebSocket write failed: %v", err)
+				s.close()
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) pingLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.send(map[string]string{"op": "ping"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close stops the writer and ping loop and closes the connection, which also
+// ends the read loop. It is safe to call more than once.
+func (s *wsSession) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+// reconnectDelay returns the wait before reconnect attempt n (1-based)
+func reconnectDelay(attempt int) time.Duration {
+	return jitteredBackoff(attempt, reconnectBaseDelay, reconnectMaxDelay)
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Candle is a confirmed OHLCV candle in the local store. Host is the REST
+// host it was fetched from, as testnet and mainnet prices differ.
+type Candle struct {
+	Host      string
+	Symbol    string
+	Interval  string
+	OpenTime  time.Time
+	CloseTime time.Time
+	Open      string
+	High      string
+	Low       string
+	Close     string
+	Volume    string
+	Turnover  string
+}
+
+// SaveCandles upserts candles keyed by (host, symbol, interval, open_time)
+func SaveCandles(ctx context.Context, candles []Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO candles (host, symbol, interval, open_time, close_time,
+			open, high, low, close, volume, turnover)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (host, symbol, interval, open_time) DO UPDATE SET
+			close_time = EXCLUDED.close_time,
+			open = EXCLUDED.open,
+			high = EXCLUDED.high,
+			low = EXCLUDED.low,
+			close = EXCLUDED.close,
+			volume = EXCLUDED.volume,
+			turnover = EXCLUDED.turnover,
+			updated_at = now()
+	`
+	batch := &pgx.Batch{}
+	for _, c := range candles {
+		batch.Queue(query, c.Host, c.Symbol, c.Interval, c.OpenTime, c.CloseTime,
+			c.Open, c.High, c.Low, c.Close, c.Volume, c.Turnover)
+	}
+	if err := DB.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save candles: %w", err)
+	}
+	return nil
+}
+
+// GetCandles returns stored candles opening in [from, to], oldest first
+func GetCandles(ctx context.Context, host, symbol, interval string, from, to time.Time) ([]Candle, error) {
+	query := `
+		SELECT host, symbol, interval, open_time, close_time,
+			open::text, high::text, low::text, close::text, volume::text, turnover::text
+		FROM candles
+		WHERE host = $1 AND symbol = $2 AND interval = $3 AND open_time >= $4 AND open_time <= $5
+		ORDER BY open_time
+	`
+	rows, err := DB.Query(ctx, query, host, symbol, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []Candle
+	for rows.Next() {
+		var c Candle
+		err := rows.Scan(&c.Host, &c.Symbol, &c.Interval, &c.OpenTime, &c.CloseTime,
+			&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.Turnover)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating candles: %w", err)
+	}
+	return candles, nil
+}
+
+// GetCandleOpenTimes returns the open times of stored candles in [from, to],
+// oldest first. It is used to find gaps without loading the candles.
+func GetCandleOpenTimes(ctx context.Context, host, symbol, interval string, from, to time.Time) ([]time.Time, error) {
+	query := `
+		SELECT open_time FROM candles
+		WHERE host = $1 AND symbol = $2 AND interval = $3 AND open_time >= $4 AND open_time <= $5
+		ORDER BY open_time
+	`
+	rows, err := DB.Query(ctx, query, host, symbol, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candle open times: %w", err)
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("failed to scan candle open time: %w", err)
+		}
+		times = append(times, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating candle open times: %w", err)
+	}
+	return times, nil
+}
+
+// CandleRange is a run of candle open times [From, To]
+type CandleRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// SaveCandleEmptyRanges records ranges the exchange has no candles for, so
+// they are not fetched again
+func SaveCandleEmptyRanges(ctx context.Context, host, symbol, interval string, ranges []CandleRange) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO candle_empty_ranges (host, symbol, interval, from_time, to_time)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (host, symbol, interval, from_time) DO UPDATE SET
+			to_time = GREATEST(candle_empty_ranges.to_time, EXCLUDED.to_time)
+	`
+	batch := &pgx.Batch{}
+	for _, r := range ranges {
+		batch.Queue(query, host, symbol, interval, r.From, r.To)
+	}
+	if err := DB.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save candle empty ranges: %w", err)
+	}
+	return nil
+}
+
+// GetCandleEmptyRanges returns the recorded empty ranges overlapping [from, to]
+func GetCandleEmptyRanges(ctx context.Context, host, symbol, interval string, from, to time.Time) ([]CandleRange, error) {
+	query := `
+		SELECT from_time, to_time FROM candle_empty_ranges
+		WHERE host = $1 AND symbol = $2 AND interval = $3 AND from_time <= $5 AND to_time >= $4
+		ORDER BY from_time
+	`
+	rows, err := DB.Query(ctx, query, host, symbol, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candle empty ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []CandleRange
+	for rows.Next() {
+		var r CandleRange
+		if err := rows.Scan(&r.From, &r.To); err != nil {
+			return nil, fmt.Errorf("failed to scan candle empty range: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating candle empty ranges: %w", err)
+	}
+	return ranges, nil
+}
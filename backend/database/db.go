@@ -58,6 +58,8 @@ func initTables() error {
 		api_key TEXT NOT NULL,
 		api_secret TEXT NOT NULL,
 		user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+		environment TEXT NOT NULL DEFAULT 'mainnet',
+		base_url TEXT NOT NULL DEFAULT '',
 		created_at TIMESTAMPTZ DEFAULT now(),
 		updated_at TIMESTAMPTZ DEFAULT now()
 	);`
@@ -68,6 +70,107 @@ func initTables() error {
 	ADD COLUMN IF NOT EXISTS api_secret TEXT NOT NULL DEFAULT '';
 	`
 
+	// Ensure environment columns exist for existing databases
+	ensureEnvironmentColumns := `
+	ALTER TABLE bybit
+	ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT 'mainnet',
+	ADD COLUMN IF NOT EXISTS base_url TEXT NOT NULL DEFAULT '';
+	`
+
+	// Create executions table (local trade ledger)
+	executionsTable := `
+	CREATE TABLE IF NOT EXISTS executions (
+		exec_id TEXT NOT NULL,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		exchange TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		order_id TEXT NOT NULL,
+		order_link_id TEXT NOT NULL DEFAULT '',
+		side TEXT NOT NULL,
+		price NUMERIC NOT NULL,
+		qty NUMERIC NOT NULL,
+		fee NUMERIC NOT NULL,
+		fee_currency TEXT NOT NULL DEFAULT '',
+		is_maker BOOLEAN NOT NULL,
+		exec_time TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT now(),
+		PRIMARY KEY (user_id, exchange, exec_id)
+	);
+	CREATE INDEX IF NOT EXISTS executions_user_time_idx ON executions (user_id, exec_time);`
+
+	// Drop candle tables from before the host key. They only cache exchange
+	// data, which is fetched again on demand.
+	dropUnkeyedCandleTables := `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'candles' AND column_name = 'host'
+		) THEN
+			DROP TABLE IF EXISTS candles;
+			DROP TABLE IF EXISTS candle_empty_ranges;
+		END IF;
+	END $$;`
+
+	// Create candles table (local OHLCV store, confirmed candles only, keyed
+	// by the REST host they came from)
+	candlesTable := `
+	CREATE TABLE IF NOT EXISTS candles (
+		host TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		interval TEXT NOT NULL,
+		open_time TIMESTAMPTZ NOT NULL,
+		close_time TIMESTAMPTZ NOT NULL,
+		open NUMERIC NOT NULL,
+		high NUMERIC NOT NULL,
+		low NUMERIC NOT NULL,
+		close NUMERIC NOT NULL,
+		volume NUMERIC NOT NULL,
+		turnover NUMERIC NOT NULL,
+		updated_at TIMESTAMPTZ DEFAULT now(),
+		PRIMARY KEY (host, symbol, interval, open_time)
+	);`
+
+	// Create candle empty ranges table (ranges Bybit has no candles for, e.g.
+	// before listing or during trading halts)
+	candleEmptyRangesTable := `
+	CREATE TABLE IF NOT EXISTS candle_empty_ranges (
+		host TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		interval TEXT NOT NULL,
+		from_time TIMESTAMPTZ NOT NULL,
+		to_time TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (host, symbol, interval, from_time)
+	);`
+
+	// Create portfolio snapshots table (net-worth history)
+	portfolioSnapshotsTable := `
+	CREATE TABLE IF NOT EXISTS portfolio_snapshots (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		exchange TEXT NOT NULL,
+		taken_at TIMESTAMPTZ NOT NULL,
+		total_usdt NUMERIC NOT NULL,
+		total_usd NUMERIC NOT NULL,
+		total_eur NUMERIC NOT NULL,
+		total_btc NUMERIC NOT NULL,
+		holdings JSONB NOT NULL DEFAULT '[]',
+		PRIMARY KEY (user_id, exchange, taken_at)
+	);`
+
+	ensureUnpricedColumn := `
+	ALTER TABLE portfolio_snapshots
+	ADD COLUMN IF NOT EXISTS unpriced TEXT[] NOT NULL DEFAULT '{}';
+	`
+
+	// Create execution sync table (how far each user's fills were fetched)
+	executionSyncTable := `
+	CREATE TABLE IF NOT EXISTS execution_sync (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		exchange TEXT NOT NULL,
+		synced_until TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (user_id, exchange)
+	);`
+
 	// Execute SQL commands
 	if _, err := DB.Exec(ctx, usersTable); err != nil {
 		return fmt.Errorf("failed to create users table: %w", err)
@@ -85,5 +188,37 @@ func initTables() error {
 		return fmt.Errorf("failed to ensure api_secret column: %w", err)
 	}
 
+	if _, err := DB.Exec(ctx, ensureEnvironmentColumns); err != nil {
+		return fmt.Errorf("failed to ensure environment columns: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, executionsTable); err != nil {
+		return fmt.Errorf("failed to create executions table: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, dropUnkeyedCandleTables); err != nil {
+		return fmt.Errorf("failed to migrate candle tables: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, candlesTable); err != nil {
+		return fmt.Errorf("failed to create candles table: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, candleEmptyRangesTable); err != nil {
+		return fmt.Errorf("failed to create candle_empty_ranges table: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, portfolioSnapshotsTable); err != nil {
+		return fmt.Errorf("failed to create portfolio_snapshots table: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, ensureUnpricedColumn); err != nil {
+		return fmt.Errorf("failed to ensure unpriced column: %w", err)
+	}
+
+	if _, err := DB.Exec(ctx, executionSyncTable); err != nil {
+		return fmt.Errorf("failed to create execution_sync table: %w", err)
+	}
+
 	return nil
 }
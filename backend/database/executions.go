@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Execution is a single trade fill stored in the local ledger
+type Execution struct {
+	ExecID      string
+	UserID      string
+	Exchange    string
+	Symbol      string
+	OrderID     string
+	OrderLinkID string
+	Side        string
+	Price       string
+	Qty         string
+	Fee         string
+	FeeCurrency string
+	IsMaker     bool
+	ExecTime    time.Time
+}
+
+// SaveExecutions inserts fills, ignoring ones already stored
+func SaveExecutions(ctx context.Context, execs []Execution) error {
+	if len(execs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO executions (exec_id, user_id, exchange, symbol, order_id, order_link_id,
+			side, price, qty, fee, fee_currency, is_maker, exec_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (user_id, exchange, exec_id) DO NOTHING
+	`
+	batch := &pgx.Batch{}
+	for _, e := range execs {
+		batch.Queue(query, e.ExecID, e.UserID, e.Exchange, e.Symbol, e.OrderID, e.OrderLinkID,
+			e.Side, e.Price, e.Qty, e.Fee, e.FeeCurrency, e.IsMaker, e.ExecTime)
+	}
+	if err := DB.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save executions: %w", err)
+	}
+	return nil
+}
+
+// GetExecutions returns stored fills for a user in [from, to], oldest first.
+// An empty symbol matches all symbols.
+func GetExecutions(ctx context.Context, userID, exchange, symbol string, from, to time.Time) ([]Execution, error) {
+	query := `
+		SELECT exec_id, user_id, exchange, symbol, order_id, order_link_id,
+			side, price::text, qty::text, fee::text, fee_currency, is_maker, exec_time
+		FROM executions
+		WHERE user_id = $1 AND exchange = $2 AND ($3 = '' OR symbol = $3)
+			AND exec_time >= $4 AND exec_time <= $5
+		ORDER BY exec_time, exec_id
+	`
+	rows, err := DB.Query(ctx, query, userID, exchange, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executions: %w", err)
+	}
+	defer rows.Close()
+
+	var execs []Execution
+	for rows.Next() {
+		var e Execution
+		err := rows.Scan(&e.ExecID, &e.UserID, &e.Exchange, &e.Symbol, &e.OrderID, &e.OrderLinkID,
+			&e.Side, &e.Price, &e.Qty, &e.Fee, &e.FeeCurrency, &e.IsMaker, &e.ExecTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan execution: %w", err)
+		}
+		execs = append(execs, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating executions: %w", err)
+	}
+	return execs, nil
+}
+
+// GetExecutionSyncTime returns how far the user's fills were fetched from the
+// exchange. ok is false when they were never synced.
+func GetExecutionSyncTime(ctx context.Context, userID, exchange string) (syncedUntil time.Time, ok bool, err error) {
+	query := `
+		SELECT synced_until
+		FROM execution_sync
+		WHERE user_id = $1 AND exchange = $2
+	`
+	err = DB.QueryRow(ctx, query, userID, exchange).Scan(&syncedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get execution sync time: %w", err)
+	}
+	return syncedUntil, true, nil
+}
+
+// SetExecutionSyncTime records that the user's fills were fetched up to
+// syncedUntil. The time never moves back, so an overlapping older sync does
+// not undo a newer one.
+func SetExecutionSyncTime(ctx context.Context, userID, exchange string, syncedUntil time.Time) error {
+	query := `
+		INSERT INTO execution_sync (user_id, exchange, synced_until)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, exchange) DO UPDATE
+		SET synced_until = GREATEST(execution_sync.synced_until, EXCLUDED.synced_until)
+	`
+	if _, err := DB.Exec(ctx, query, userID, exchange, syncedUntil); err != nil {
+		return fmt.Errorf("failed to set execution sync time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PortfolioSnapshot is a user's net worth at one point in time. Holdings is
+// the JSON encoded per-coin valuation; Unpriced lists held coins that could
+// not be priced and are missing from the totals.
+type PortfolioSnapshot struct {
+	UserID    string
+	Exchange  string
+	TakenAt   time.Time
+	TotalUSDT string
+	TotalUSD  string
+	TotalEUR  string
+	TotalBTC  string
+	Holdings  []byte
+	Unpriced  []string
+}
+
+// SavePortfolioSnapshot stores a snapshot, replacing one taken at the same time
+func SavePortfolioSnapshot(ctx context.Context, s PortfolioSnapshot) error {
+	query := `
+		INSERT INTO portfolio_snapshots (user_id, exchange, taken_at,
+			total_usdt, total_usd, total_eur, total_btc, holdings, unpriced)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, exchange, taken_at) DO UPDATE SET
+			total_usdt = EXCLUDED.total_usdt,
+			total_usd = EXCLUDED.total_usd,
+			total_eur = EXCLUDED.total_eur,
+			total_btc = EXCLUDED.total_btc,
+			holdings = EXCLUDED.holdings,
+			unpriced = EXCLUDED.unpriced
+	`
+	_, err := DB.Exec(ctx, query, s.UserID, s.Exchange, s.TakenAt,
+		s.TotalUSDT, s.TotalUSD, s.TotalEUR, s.TotalBTC, string(s.Holdings), s.Unpriced)
+	if err != nil {
+		return fmt.Errorf("failed to save portfolio snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetPortfolioSnapshots returns snapshot totals taken since from, oldest first.
+// With a positive bucket only the last snapshot of every bucket is returned;
+// holdings are not loaded.
+func GetPortfolioSnapshots(ctx context.Context, userID, exchange string, from time.Time, bucket time.Duration) ([]PortfolioSnapshot, error) {
+	query := `
+		SELECT DISTINCT ON (bucket) user_id, exchange, taken_at,
+			total_usdt::text, total_usd::text, total_eur::text, total_btc::text, unpriced
+		FROM (
+			SELECT *,
+				CASE WHEN $4::bigint > 0
+					THEN floor(extract(epoch FROM taken_at) / $4::bigint)
+					ELSE extract(epoch FROM taken_at)
+				END AS bucket
+			FROM portfolio_snapshots
+			WHERE user_id = $1 AND exchange = $2 AND taken_at >= $3
+		) s
+		ORDER BY bucket, taken_at DESC
+	`
+	rows, err := DB.Query(ctx, query, userID, exchange, from, int64(bucket/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []PortfolioSnapshot
+	for rows.Next() {
+		var s PortfolioSnapshot
+		err := rows.Scan(&s.UserID, &s.Exchange, &s.TakenAt,
+			&s.TotalUSDT, &s.TotalUSD, &s.TotalEUR, &s.TotalBTC, &s.Unpriced)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating portfolio snapshots: %w", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,206 @@
+package exchange
+
+import (
+	"context"
+)
+
+// =============================================================================
+// Data structures
+// =============================================================================
+
+// Holding represents a spot balance held on an exchange
+type Holding struct {
+	Coin   string `json:"coin"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+// CoinBalance represents the wallet balance of a single coin
+type CoinBalance struct {
+	Coin            string `json:"coin"`
+	WalletBalance   string `json:"walletBalance"`
+	TransferBalance string `json:"transferBalance"`
+	Locked          string `json:"locked"`
+	Bonus           string `json:"bonus"`
+}
+
+// Ticker represents the latest market data and 24h statistics for a symbol.
+// Fields the exchange does not provide are left empty.
+type Ticker struct {
+	Symbol       string `json:"symbol"`
+	Base         string `json:"base"`
+	Quote        string `json:"quote"`
+	LastPrice    string `json:"lastPrice"`
+	Bid1Price    string `json:"bid1Price"`
+	Bid1Size     string `json:"bid1Size"`
+	Ask1Price    string `json:"ask1Price"`
+	Ask1Size     string `json:"ask1Size"`
+	PrevPrice24h string `json:"prevPrice24h"`
+	Price24hPcnt string `json:"price24hPcnt"`
+	HighPrice24h string `json:"highPrice24h"`
+	LowPrice24h  string `json:"lowPrice24h"`
+	Volume24h    string `json:"volume24h"`
+	Turnover24h  string `json:"turnover24h"`
+	Time         int64  `json:"time"`
+}
+
+// Pair identifies a spot market by its base and quote coin
+type Pair struct {
+	Base   string `json:"base"`
+	Quote  string `json:"quote"`
+	Symbol string `json:"symbol"` // exchange-specific instrument name, e.g. "ETHBTC"
+}
+
+// PriceData represents a single streamed price update. Ticker holds the full
+// merged ticker state after the update.
+type PriceData struct {
+	Symbol string `json:"symbol"`
+	Base   string `json:"base"`
+	Quote  string `json:"quote"`
+	Price  string `json:"price"`
+	Time   int64  `json:"time"`
+	Ticker Ticker `json:"ticker"`
+}
+
+// OrderBookLevel is a single price level of an order book
+type OrderBookLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// OrderBook is an L2 order book snapshot. Bids are sorted best (highest)
+// first, asks best (lowest) first.
+type OrderBook struct {
+	Symbol   string           `json:"symbol"`
+	Base     string           `json:"base"`
+	Quote    string           `json:"quote"`
+	Depth    int              `json:"depth"`
+	Bids     []OrderBookLevel `json:"bids"`
+	Asks     []OrderBookLevel `json:"asks"`
+	BestBid  string           `json:"bestBid"`
+	BestAsk  string           `json:"bestAsk"`
+	Spread   string           `json:"spread"`
+	UpdateID int64            `json:"updateId"`
+	Time     int64            `json:"time"`
+}
+
+// Trade is a single public trade of a market
+type Trade struct {
+	Symbol  string `json:"symbol"`
+	TradeID string `json:"tradeId"`
+	Price   string `json:"price"`
+	Size    string `json:"size"`
+	Side    string `json:"side"` // taker side, "Buy" or "Sell"
+	Time    int64  `json:"time"`
+}
+
+// Candle is an OHLCV candle. Start and End are the first and last millisecond
+// of the interval; Confirmed is false while the candle is still in progress.
+type Candle struct {
+	Symbol    string `json:"symbol"`
+	Interval  string `json:"interval"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Open      string `json:"open"`
+	High      string `json:"high"`
+	Low       string `json:"low"`
+	Close     string `json:"close"`
+	Volume    string `json:"volume"`
+	Turnover  string `json:"turnover"`
+	Confirmed bool   `json:"confirmed"`
+}
+
+// IconEntry holds icon URLs (and optionally inlined data URLs) for a coin
+type IconEntry struct {
+	Coin string `json:"coin"`
+	// Back-compat: keep iconUrl (will mirror darkUrl by default)
+	IconURL      string `json:"iconUrl,omitempty"`
+	DarkURL      string `json:"darkUrl,omitempty"`
+	LightURL     string `json:"lightUrl,omitempty"`
+	DarkDataURL  string `json:"darkDataUrl,omitempty"`
+	LightDataURL string `json:"lightDataUrl,omitempty"`
+}
+
+// =============================================================================
+// Connector interfaces
+// =============================================================================
+
+// Connector is implemented by every supported exchange. Symbols passed to
+// GetTicker and ResolvePair may be a bare coin ("btc"), an instrument symbol
+// ("ETHBTC") or a base/quote pair ("eth/btc"); each connector maps them onto
+// its own instrument naming.
+type Connector interface {
+	// Name returns the registry key of the exchange, e.g. "bybit"
+	Name() string
+
+	// GetHoldings returns all spot holdings of the user
+	GetHoldings(ctx context.Context, userID string) ([]Holding, error)
+
+	// GetBalance returns the balance of a single coin for the user
+	GetBalance(ctx context.Context, userID string, coin string) (*CoinBalance, error)
+
+	// GetTicker returns the latest ticker for a symbol
+	GetTicker(ctx context.Context, symbol string) (*Ticker, error)
+
+	// ResolvePair maps a coin, symbol or base/quote pair to a listed market
+	ResolvePair(ctx context.Context, symbol string) (*Pair, error)
+
+	// SubscribePair starts streaming price updates for a market, delivered
+	// according to opts when the receiver falls behind
+	SubscribePair(pair Pair, opts SubscribeOptions) (chan PriceData, error)
+
+	// UnsubscribePair stops delivering updates to a channel returned by SubscribePair
+	UnsubscribePair(pair Pair, ch chan PriceData)
+}
+
+// MarketSelector is optionally implemented by connectors whose public market
+// data depends on the user, e.g. one trading on a testnet
+type MarketSelector interface {
+	// SelectMarkets serves public market data for the markets userID trades on
+	SelectMarkets(userID string)
+}
+
+// IconProvider is optionally implemented by connectors that can resolve coin icons
+type IconProvider interface {
+	GetCoinIconURLs(coins []string) ([]IconEntry, error)
+	PrefetchCoinIcons(coins []string)
+}
+
+// OrderBookStreamer is optionally implemented by connectors that can stream L2 depth
+type OrderBookStreamer interface {
+	// GetOrderBook returns the current book, from the live stream when subscribed
+	GetOrderBook(ctx context.Context, pair Pair, depth int) (*OrderBook, error)
+
+	// SubscribeOrderBook streams book snapshots after every applied update
+	SubscribeOrderBook(pair Pair, depth int) (chan OrderBook, error)
+
+	// UnsubscribeOrderBook stops delivering books to a channel returned by SubscribeOrderBook
+	UnsubscribeOrderBook(pair Pair, depth int, ch chan OrderBook)
+}
+
+// TradeStreamer is optionally implemented by connectors that can stream public trades
+type TradeStreamer interface {
+	// SubscribeTrades streams every public trade of a market
+	SubscribeTrades(pair Pair) (chan Trade, error)
+
+	// UnsubscribeTrades stops delivering trades to a channel returned by SubscribeTrades
+	UnsubscribeTrades(pair Pair, ch chan Trade)
+}
+
+// TradeStatsProvider is optionally implemented by trade streamers that
+// expose delivery counters of their trade subscriptions
+type TradeStatsProvider interface {
+	TradeStreamStats() []SubscriberStats
+}
+
+// KlineProvider is optionally implemented by connectors that serve candlestick data
+type KlineProvider interface {
+	// GetKlines returns candles in [start, end] (ms), oldest first
+	GetKlines(ctx context.Context, pair Pair, interval string, start, end int64) ([]Candle, error)
+
+	// SubscribeKlines streams updates of the current candle, including the final confirmed one
+	SubscribeKlines(pair Pair, interval string) (chan Candle, error)
+
+	// UnsubscribeKlines stops delivering candles to a channel returned by SubscribeKlines
+	UnsubscribeKlines(pair Pair, interval string, ch chan Candle)
+}
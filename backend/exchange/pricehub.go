@@ -0,0 +1,254 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryPolicy decides what happens when a price subscriber falls behind
+type DeliveryPolicy string
+
+const (
+	// PolicyCoalesce keeps only the latest unread update; older ones are replaced
+	PolicyCoalesce DeliveryPolicy = "coalesce"
+	// PolicyBounded buffers up to Buffer updates and drops new ones when full
+	PolicyBounded DeliveryPolicy = "bounded"
+	// PolicyThrottle delivers at most one update per Interval, always the latest
+	PolicyThrottle DeliveryPolicy = "throttle"
+)
+
+// Defaults applied to zero-valued SubscribeOptions fields
+const (
+	defaultBoundedBuffer    = 10
+	defaultThrottleInterval = 250 * time.Millisecond
+)
+
+// SubscribeOptions selects the delivery policy of a price subscription. The
+// zero value is a bounded buffer of 10 updates.
+type SubscribeOptions struct {
+	Policy   DeliveryPolicy
+	Buffer   int           // PolicyBounded: channel capacity
+	Interval time.Duration // PolicyThrottle: minimum time between deliveries
+}
+
+// SubscriberStats reports delivery counters of one price or trade subscriber
+type SubscriberStats struct {
+	Symbol    string         `json:"symbol"`
+	Policy    DeliveryPolicy `json:"policy"`
+	Delivered uint64         `json:"delivered"`
+	Dropped   uint64         `json:"dropped"`   // discarded because the buffer was full
+	Coalesced uint64         `json:"coalesced"` // replaced by a newer update before being read
+}
+
+// PriceStatsProvider is optionally implemented by connectors that expose
+// delivery counters of their price subscriptions
+type PriceStatsProvider interface {
+	PriceStreamStats() []SubscriberStats
+}
+
+// priceSubscriber is a single channel registered with a PriceHub
+type priceSubscriber struct {
+	symbol string
+	opts   SubscribeOptions
+	ch     chan PriceData
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+
+	// PolicyThrottle only: the latest undelivered update and the flush loop
+	pendingMu sync.Mutex
+	pending   *PriceData
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// PriceHub fans price updates out to subscribers, applying each subscriber's
+// delivery policy so a slow reader never blocks the others
+type PriceHub struct {
+	mu   sync.RWMutex
+	subs map[string][]*priceSubscriber
+}
+
+// NewPriceHub creates an empty hub
+func NewPriceHub() *PriceHub {
+	return &PriceHub{subs: make(map[string][]*priceSubscriber)}
+}
+
+// Add registers a subscriber for symbol and returns its channel together with
+// the number of subscribers of the symbol, including the new one
+func (h *PriceHub) Add(symbol string, opts SubscribeOptions) (chan PriceData, int) {
+	sub := &priceSubscriber{symbol: symbol, opts: withDefaults(opts)}
+	switch sub.opts.Policy {
+	case PolicyBounded:
+		sub.ch = make(chan PriceData, sub.opts.Buffer)
+	default:
+		sub.ch = make(chan PriceData, 1)
+	}
+	if sub.opts.Policy == PolicyThrottle {
+		sub.stop = make(chan struct{})
+		sub.stopped = make(chan struct{})
+		go sub.flushLoop()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[symbol] = append(h.subs[symbol], sub)
+	return sub.ch, len(h.subs[symbol])
+}
+
+// Remove unregisters and closes ch. It returns the number of subscribers
+// left for symbol.
+func (h *PriceHub) Remove(symbol string, ch chan PriceData) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[symbol]
+	for i, sub := range subs {
+		if sub.ch == ch {
+			h.subs[symbol] = append(subs[:i], subs[i+1:]...)
+			sub.close()
+			break
+		}
+	}
+	remaining := len(h.subs[symbol])
+	if remaining == 0 {
+		delete(h.subs, symbol)
+	}
+	return remaining
+}
+
+// Publish delivers an update to every subscriber of its symbol without blocking
+func (h *PriceHub) Publish(data PriceData) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, sub := range h.subs[data.Symbol] {
+		sub.deliver(data)
+	}
+}
+
+// Stats returns the counters of every subscriber, ordered by symbol
+func (h *PriceHub) Stats() []SubscriberStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []SubscriberStats
+	for _, subs := range h.subs {
+		for _, sub := range subs {
+			out = append(out, SubscriberStats{
+				Symbol:    sub.symbol,
+				Policy:    sub.opts.Policy,
+				Delivered: sub.delivered.Load(),
+				Dropped:   sub.dropped.Load(),
+				Coalesced: sub.coalesced.Load(),
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// CloseAll unregisters and closes every subscriber
+func (h *PriceHub) CloseAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.subs {
+		for _, sub := range subs {
+			sub.close()
+		}
+	}
+	h.subs = make(map[string][]*priceSubscriber)
+}
+
+func withDefaults(opts SubscribeOptions) SubscribeOptions {
+	if opts.Policy == "" {
+		opts.Policy = PolicyBounded
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultBoundedBuffer
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultThrottleInterval
+	}
+	return opts
+}
+
+func (s *priceSubscriber) deliver(data PriceData) {
+	switch s.opts.Policy {
+	case PolicyCoalesce:
+		s.sendLatest(data)
+	case PolicyThrottle:
+		s.pendingMu.Lock()
+		if s.pending != nil {
+			s.coalesced.Add(1)
+		}
+		s.pending = &data
+		s.pendingMu.Unlock()
+	default:
+		select {
+		case s.ch <- data:
+			s.delivered.Add(1)
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// sendLatest puts data into the 1-slot channel, replacing an unread update
+func (s *priceSubscriber) sendLatest(data PriceData) {
+	select {
+	case s.ch <- data:
+		s.delivered.Add(1)
+		return
+	default:
+	}
+	select {
+	case <-s.ch:
+		s.coalesced.Add(1)
+		s.delivered.Add(^uint64(0)) // the replaced update was never read
+	default:
+	}
+	select {
+	case s.ch <- data:
+		s.delivered.Add(1)
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// flushLoop delivers the latest pending update once per interval
+func (s *priceSubscriber) flushLoop() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.pendingMu.Lock()
+			pending := s.pending
+			s.pending = nil
+			s.pendingMu.Unlock()
+			if pending != nil {
+				s.sendLatest(*pending)
+			}
+		}
+	}
+}
+
+// close stops the flush loop, if any, before closing the channel so no send
+// can race with the close
+func (s *priceSubscriber) close() {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.stopped
+	}
+	close(s.ch)
+}
@@ -0,0 +1,186 @@
+package exchange
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// publishN publishes prices 1..n for symbol without reading
+func publishN(h *PriceHub, symbol string, n int) {
+	for i := 1; i <= n; i++ {
+		h.Publish(PriceData{Symbol: symbol, Price: strconv.Itoa(i)})
+	}
+}
+
+// drain returns the prices buffered in ch without blocking
+func drain(ch chan PriceData) []string {
+	var prices []string
+	for {
+		select {
+		case d := <-ch:
+			prices = append(prices, d.Price)
+		default:
+			return prices
+		}
+	}
+}
+
+func statsOf(t *testing.T, h *PriceHub) SubscriberStats {
+	t.Helper()
+	stats := h.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("got %d subscribers, want 1", len(stats))
+	}
+	return stats[0]
+}
+
+func TestPriceHubSlowReader(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          SubscribeOptions
+		publish       int
+		wantPrices    []string
+		wantDelivered uint64
+		wantDropped   uint64
+		wantCoalesced uint64
+	}{
+		{
+			name:          "bounded keeps the first updates and drops the rest",
+			opts:          SubscribeOptions{Policy: PolicyBounded, Buffer: 3},
+			publish:       5,
+			wantPrices:    []string{"1", "2", "3"},
+			wantDelivered: 3,
+			wantDropped:   2,
+		},
+		{
+			name:          "zero options are a bounded buffer of 10",
+			opts:          SubscribeOptions{},
+			publish:       12,
+			wantPrices:    []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"},
+			wantDelivered: 10,
+			wantDropped:   2,
+		},
+		{
+			name:          "coalesce keeps only the latest update",
+			opts:          SubscribeOptions{Policy: PolicyCoalesce},
+			publish:       5,
+			wantPrices:    []string{"5"},
+			wantDelivered: 1,
+			wantCoalesced: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewPriceHub()
+			defer h.CloseAll()
+			ch, _ := h.Add("BTCUSDT", tt.opts)
+
+			publishN(h, "BTCUSDT", tt.publish)
+
+			if got := drain(ch); !slices.Equal(got, tt.wantPrices) {
+				t.Errorf("received %v, want %v", got, tt.wantPrices)
+			}
+			s := statsOf(t, h)
+			if s.Delivered != tt.wantDelivered || s.Dropped != tt.wantDropped || s.Coalesced != tt.wantCoalesced {
+				t.Errorf("stats delivered=%d dropped=%d coalesced=%d, want %d/%d/%d",
+					s.Delivered, s.Dropped, s.Coalesced, tt.wantDelivered, tt.wantDropped, tt.wantCoalesced)
+			}
+		})
+	}
+}
+
+func TestPriceHubCoalesceDoesNotCountReplacedUpdates(t *testing.T) {
+	h := NewPriceHub()
+	defer h.CloseAll()
+	ch, _ := h.Add("BTCUSDT", SubscribeOptions{Policy: PolicyCoalesce})
+
+	// Every read sees the latest update; replaced ones never count as delivered
+	publishN(h, "BTCUSDT", 3)
+	if got := drain(ch); !slices.Equal(got, []string{"3"}) {
+		t.Fatalf("received %v, want [3]", got)
+	}
+	h.Publish(PriceData{Symbol: "BTCUSDT", Price: "4"})
+	if got := drain(ch); !slices.Equal(got, []string{"4"}) {
+		t.Fatalf("received %v, want [4]", got)
+	}
+
+	s := statsOf(t, h)
+	if s.Delivered != 2 || s.Coalesced != 2 || s.Dropped != 0 {
+		t.Errorf("stats delivered=%d dropped=%d coalesced=%d, want 2/0/2", s.Delivered, s.Dropped, s.Coalesced)
+	}
+}
+
+func TestPriceHubThrottle(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	h := NewPriceHub()
+	defer h.CloseAll()
+	ch, _ := h.Add("BTCUSDT", SubscribeOptions{Policy: PolicyThrottle, Interval: interval})
+
+	publishN(h, "BTCUSDT", 5)
+	if got := drain(ch); len(got) != 0 {
+		t.Fatalf("received %v before the first flush, want nothing", got)
+	}
+
+	select {
+	case d := <-ch:
+		if d.Price != "5" {
+			t.Errorf("flushed price %s, want 5", d.Price)
+		}
+	case <-time.After(10 * interval):
+		t.Fatal("no update flushed")
+	}
+
+	// Nothing pending, so the next flushes deliver nothing
+	time.Sleep(3 * interval)
+	if got := drain(ch); len(got) != 0 {
+		t.Errorf("received %v without new updates, want nothing", got)
+	}
+
+	// An unread flush is replaced by the next one
+	publishN(h, "BTCUSDT", 2)
+	time.Sleep(3 * interval)
+	h.Publish(PriceData{Symbol: "BTCUSDT", Price: "9"})
+	time.Sleep(3 * interval)
+	if got := drain(ch); !slices.Equal(got, []string{"9"}) {
+		t.Errorf("received %v, want [9]", got)
+	}
+
+	s := statsOf(t, h)
+	// 5 published -> 1 delivered, 4 coalesced while pending;
+	// 2 published -> 1 pending replaced, flushed "2" replaced by "9" in the channel
+	if s.Delivered != 2 || s.Coalesced != 6 || s.Dropped != 0 {
+		t.Errorf("stats delivered=%d dropped=%d coalesced=%d, want 2/0/6", s.Delivered, s.Dropped, s.Coalesced)
+	}
+}
+
+func TestPriceHubRemoveClosesChannel(t *testing.T) {
+	h := NewPriceHub()
+	first, n := h.Add("BTCUSDT", SubscribeOptions{Policy: PolicyThrottle})
+	second, m := h.Add("BTCUSDT", SubscribeOptions{})
+	if n != 1 || m != 2 {
+		t.Fatalf("subscriber counts %d, %d, want 1, 2", n, m)
+	}
+
+	if remaining := h.Remove("BTCUSDT", first); remaining != 1 {
+		t.Errorf("remaining %d, want 1", remaining)
+	}
+	if _, open := <-first; open {
+		t.Error("removed channel is still open")
+	}
+
+	// Publishing to the remaining subscriber must not touch the closed one
+	h.Publish(PriceData{Symbol: "BTCUSDT", Price: "1"})
+	if got := drain(second); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("received %v, want [1]", got)
+	}
+
+	if remaining := h.Remove("BTCUSDT", second); remaining != 0 {
+		t.Errorf("remaining %d, want 0", remaining)
+	}
+	if stats := h.Stats(); len(stats) != 0 {
+		t.Errorf("stats of removed subscribers: %v", stats)
+	}
+}
@@ -0,0 +1,51 @@
+package exchange
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds the available connectors keyed by exchange name
+type Registry struct {
+	mu         sync.RWMutex
+	connectors map[string]Connector
+}
+
+// NewRegistry creates an empty connector registry
+func NewRegistry() *Registry {
+	return &Registry{
+		connectors: make(map[string]Connector),
+	}
+}
+
+// Register adds a connector, replacing any connector with the same name
+func (r *Registry) Register(c Connector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connectors[strings.ToLower(c.Name())] = c
+}
+
+// Get returns the connector registered under name
+func (r *Registry) Get(name string) (Connector, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.connectors[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("exchange %q is not registered", name)
+	}
+	return c, nil
+}
+
+// Names returns the names of all registered exchanges in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.connectors))
+	for name := range r.connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,20 +2,18 @@ package queue
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/hibiken/asynq"
 )
 
-const (
-	TaskSayHello = "task:say_hello"
-)
-
 type Queue struct {
-	client *asynq.Client
-	server *asynq.Server
+	client    *asynq.Client
+	server    *asynq.Server
+	handlers  map[string]asynq.HandlerFunc
+	schedules map[string]time.Duration
 }
 
 func NewQueue(redisAddr string) *Queue {
@@ -31,19 +29,25 @@ func NewQueue(redisAddr string) *Queue {
 	client := asynq.NewClient(r)
 
 	q := &Queue{
-		client: client,
-		server: server,
+		client:    client,
+		server:    server,
+		handlers:  make(map[string]asynq.HandlerFunc),
+		schedules: make(map[string]time.Duration),
 	}
 
 	return q
 }
 
+// HandleFunc registers a handler for a task type. It must be called before Start.
+func (q *Queue) HandleFunc(taskType string, handler func(context.Context, *asynq.Task) error) {
+	q.handlers[taskType] = handler
+}
+
 func (q *Queue) Start() {
 	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskSayHello, func(ctx context.Context, t *asynq.Task) error {
-		fmt.Println("Hi Redis is running 🚀")
-		return nil
-	})
+	for taskType, handler := range q.handlers {
+		mux.Handle(taskType, handler)
+	}
 
 	go func() {
 		if err := q.server.Run(mux); err != nil {
@@ -52,20 +56,36 @@ func (q *Queue) Start() {
 	}()
 }
 
-func (q *Queue) EnqueueHello() error {
-	task := asynq.NewTask(TaskSayHello, nil)
-	_, err := q.client.Enqueue(task, asynq.MaxRetry(1))
+// Enqueue adds a task to the queue
+func (q *Queue) Enqueue(task *asynq.Task, opts ...asynq.Option) error {
+	_, err := q.client.Enqueue(task, opts...)
 	return err
 }
 
+// Schedule enqueues a task without payload when StartScheduler runs and then
+// every interval. It must be called before StartScheduler.
+func (q *Queue) Schedule(taskType string, interval time.Duration) {
+	q.schedules[taskType] = interval
+}
+
 func (q *Queue) StartScheduler() {
-	ticker := time.NewTicker(1 * time.Minute)
+	for taskType, interval := range q.schedules {
+		ticker := time.NewTicker(interval)
 
-	go func() {
-		for range ticker.C {
-			if err := q.EnqueueHello(); err != nil {
-				log.Println("Error to add enqueue:", err)
+		go func(taskType string, interval time.Duration) {
+			q.enqueueScheduled(taskType, interval)
+			for range ticker.C {
+				q.enqueueScheduled(taskType, interval)
 			}
-		}
-	}()
+		}(taskType, interval)
+	}
+}
+
+func (q *Queue) enqueueScheduled(taskType string, interval time.Duration) {
+	// Unique keeps a slow run from piling up duplicates
+	task := asynq.NewTask(taskType, nil)
+	err := q.Enqueue(task, asynq.MaxRetry(1), asynq.Unique(interval))
+	if err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		log.Println("Error to add enqueue:", err)
+	}
 }
@@ -44,7 +44,7 @@ const BybitForm: React.FC = () => {
         PrefetchCoinIcons(coins).catch(() => {});
       } catch (e: any) {
         console.error('Error in useEffect:', e);
-        setError(String(e));
+        setError(e?.message ?? String(e));
       } finally {
         setLoading(false);
       }
@@ -29,7 +29,7 @@ const CoinBalanceSection: React.FC<{ coinSymbol: string }> = ({ coinSymbol }) =>
         setBalance(balance || null);
       } catch (e: any) {
         console.error('Failed to fetch balance:', e);
-        setError(String(e));
+        setError(e?.message ?? String(e));
       } finally {
         setLoading(false);
       }
@@ -141,7 +141,7 @@ const CoinDetail: React.FC = () => {
         }
 
       } catch (e: any) {
-        setError(String(e));
+        setError(e?.message ?? String(e));
       } finally {
         setLoading(false);
       }
@@ -34,7 +34,11 @@ func main() {
 	authService := auth.NewAuthService()
 	bybitService := bybit.NewBybitService()
 
+	// Register exchange connectors used by the App bindings
+	app.exchanges.Register(bybit.NewConnector(bybitService))
+
 	q := queue.NewQueue("localhost:6379")
+	bybit.RegisterTasks(q, bybitService)
 	q.Start()
 	q.StartScheduler()
 	app.queue = q
@@ -49,6 +53,7 @@ func main() {
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
+		ErrorFormatter:   formatBindingError,
 		OnStartup: func(ctx context.Context) {
 			app.startup(ctx)
 			// pass runtime ctx to bybit package for EventsEmit